@@ -1,7 +1,13 @@
 package lunar
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,12 +23,18 @@ type Lunar interface {
 
 // App represents a single application, an application has a unique app id and manage multiple namespaces.
 type App struct {
+	pollFailures    int64     // number of consecutive long poll failures, the first field to be 64-bit aligned for atomic
 	Options                   // inherited options
 	ID              string    // app id
 	Client          ApolloAPI // the apollo client
 	releaseKeyMap   sync.Map  // key: namespace, value: release key
 	notificationMap sync.Map  // key: namespace, value: notification id
+	negativeMap     sync.Map  // key: namespace known to be empty or missing, value: expiry time, kept after expiry
+	parsedMap       sync.Map  // key: namespace, value: *parsedContent
+	contentMap      sync.Map  // key: namespace, value: *memoContent
+	decryptedMap    sync.Map  // key: namespace, value: *decryptedValues
 	Cache           Cache
+	flights         flightGroup // coalesces concurrent fetches of the same namespace
 	watchChan       chan Notification
 	errChan         chan error
 	stopChan        chan bool
@@ -39,9 +51,10 @@ func New(appID string, opts ...Option) *App {
 		watchChan: make(chan Notification),
 		errChan:   make(chan error),
 		stopChan:  make(chan bool, 1),
+		Cache:     new(MemoryCache), // the default cache is not shared, so it needs no scoping
 	}
 
-	app.UseClient(NewApolloClient(appID, opts...)).UseCache(new(MemoryCache))
+	app.UseClient(NewApolloClient(appID, opts...))
 
 	return app
 }
@@ -53,12 +66,20 @@ func (app *App) UseClient(client ApolloAPI) *App {
 	return app
 }
 
-// UseCache sets the underlying cache
+// UseCache sets the underlying cache. If the cache implements CacheScoper, the app uses a view of it
+// scoped by app id and cluster, so that one cache can be shared by multiple apps.
+// Custom caches can be wrapped by NewScopedCache to get the same behavior.
 func (app *App) UseCache(c Cache) *App {
-	if c != nil {
-		app.Cache = c
+	if c == nil {
+		return app
 	}
 
+	if s, ok := c.(CacheScoper); ok {
+		c = s.Scope(app.ID, app.Cluster)
+	}
+
+	app.Cache = c
+
 	return app
 }
 
@@ -69,6 +90,25 @@ func (app *App) UseLogger(l Logger) *App {
 	return app
 }
 
+// CacheStats gets the statistics of the underlying cache,
+// it's empty if the cache does not implement CacheStatsProvider, wrap it by NewStatsCache to collect statistics.
+func (app *App) CacheStats() CacheStats {
+	if p, ok := app.Cache.(CacheStatsProvider); ok {
+		return p.CacheStats()
+	}
+
+	return CacheStats{Namespaces: make(map[string]NamespaceStats)}
+}
+
+// ClockSkew gets the clock skew of apollo server if the client measures it, see ApolloClient.ClockSkew
+func (app *App) ClockSkew() time.Duration {
+	if c, ok := app.Client.(ClockSkewProvider); ok {
+		return c.ClockSkew()
+	}
+
+	return 0
+}
+
 // GetReleaseKeys gets namespace and release key map
 func (app *App) GetReleaseKeys() map[string]string {
 	m := make(map[string]string)
@@ -92,12 +132,24 @@ func (app *App) GetValue(key string) (string, error) {
 
 // GetValueInNamespace gets value of key in given namespace
 func (app *App) GetValueInNamespace(key string, namespace string) (string, error) {
-	items, err := app.GetItemsInNamespace(namespace)
-	if err != nil {
+	return app.GetValueInNamespaceContext(context.Background(), key, namespace)
+}
+
+// GetValueInNamespaceContext gets value of key in given namespace,
+// the context carries the parent span and cancels the request to apollo on cache miss
+func (app *App) GetValueInNamespaceContext(ctx context.Context, key string, namespace string) (string, error) {
+	items, itemsErr := app.getItems(ctx, namespace)
+	if err := errorOfKey(itemsErr, key); err != nil {
 		return "", err
 	}
 
-	return items.Get(key), nil
+	if !app.Interpolation {
+		return items.Get(key), nil
+	}
+
+	v, _, err := app.newInterpolator(ctx, namespace, items, itemsErr).lookupValue(normalizeNamespace(namespace), key)
+
+	return v, err
 }
 
 // GetItems gets all the items in default namespace
@@ -107,48 +159,371 @@ func (app *App) GetItems() (Items, error) {
 
 // GetContent gets the content of given namespace, if the format is properties then will return json string
 func (app *App) GetContent(namespace string) (string, error) {
-	// try to get from cache first
-	items := app.Cache.GetItems(namespace)
-
-	var err error
-	if len(items) == 0 {
-		items, err = app.GetNamespaceFromApollo(namespace)
-		if err != nil {
-			return "", err
-		}
+	items, err := app.getRawItems(context.Background(), namespace)
+	if err != nil {
+		return "", err
 	}
 
 	if !IsProperties(namespace) {
 		return items.Get("content"), nil
 	}
 
-	return items.String(), nil
+	return app.getMemoContent(namespace, items), nil
+}
+
+// memoContent is the json string of properties namespace at a release
+type memoContent struct {
+	releaseKey string
+	items      Items
+	content    string
+}
+
+// getMemoContent gets the json string of items, the result is cached per release key.
+// The items are compared as well, because the cache may be shared and updated by others.
+func (app *App) getMemoContent(namespace string, items Items) string {
+	namespace = normalizeNamespace(namespace)
+	releaseKey := app.loadReleaseKey(namespace)
+
+	if v, ok := app.contentMap.Load(namespace); ok {
+		memo := v.(*memoContent)
+		if releaseKey != "" && memo.releaseKey == releaseKey && memo.items.equal(items) {
+			return memo.content
+		}
+	}
+
+	content := items.json()
+	if releaseKey != "" {
+		app.contentMap.Store(namespace, &memoContent{releaseKey: releaseKey, items: items, content: content})
+	}
+
+	return content
 }
 
 // GetItemsInNamespace gets all the items in given namespace.
+// The content of json, yaml and xml namespaces is flattened to dot-keys like properties namespaces, e.g. server.ports[0],
+// and the raw content is kept in key content.
+// Placeholders in values are resolved if interpolation is enabled.
+// If values of some keys can not be decrypted, the other items are returned with a *DecryptError.
 func (app *App) GetItemsInNamespace(namespace string) (Items, error) {
+	return app.GetItemsInNamespaceContext(context.Background(), namespace)
+}
+
+// GetItemsInNamespaceContext gets all the items in given namespace,
+// the context carries the parent span and cancels the request to apollo on cache miss
+func (app *App) GetItemsInNamespaceContext(ctx context.Context, namespace string) (Items, error) {
+	items, err := app.getItems(ctx, namespace)
+	var decryptErr *DecryptError
+	if err != nil && !errors.As(err, &decryptErr) {
+		return nil, err
+	}
+
+	if !app.Interpolation {
+		// items may be shared with the cache
+		return items.Clone(), err
+	}
+
+	resolved, resolveErr := app.newInterpolator(ctx, namespace, items, err).resolveItems(normalizeNamespace(namespace), items)
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	return resolved, err
+}
+
+// getItems gets all the items in given namespace with values decrypted, placeholders are not resolved.
+// The items may be shared with the cache, so they must not be modified.
+// Keys failed to decrypt are dropped from the items and reported by a *DecryptError.
+// The parsed keys of json, yaml and xml namespaces are added alongside the raw content, which is kept in key content,
+// and only the raw content is returned if it can not be parsed.
+func (app *App) getItems(ctx context.Context, namespace string) (Items, error) {
+	items, err := app.getRawItems(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if isStructured(namespace) {
+		if parsed, err := app.parseItems(namespace, items); err == nil {
+			items = parsed.view
+		}
+	}
+
+	return app.decryptItems(namespace, items)
+}
+
+// decryptItems decrypts values like ENC(ciphertext) if there is a decryptor, items are copied rather than modified
+// so that plaintext never goes into cache. Decrypted values are cached per release key.
+func (app *App) decryptItems(namespace string, items Items) (Items, error) {
+	if app.Decryptor == nil {
+		return items, nil
+	}
+
+	namespace = normalizeNamespace(namespace)
+
+	var cache *decryptedValues
+	if releaseKey := app.loadReleaseKey(namespace); releaseKey != "" {
+		if v, ok := app.decryptedMap.Load(namespace); ok && v.(*decryptedValues).releaseKey == releaseKey {
+			cache = v.(*decryptedValues)
+		} else {
+			cache = &decryptedValues{releaseKey: releaseKey}
+			app.decryptedMap.Store(namespace, cache)
+		}
+	}
+
+	var decrypted Items
+	var errs map[string]error
+	for k, v := range items {
+		ciphertext, ok := unwrapEncrypted(v)
+		if !ok {
+			continue
+		}
+
+		if decrypted == nil {
+			decrypted = items.Clone()
+		}
+
+		if cache != nil {
+			if plaintext, ok := cache.values.Load(ciphertext); ok {
+				decrypted[k] = plaintext.(string)
+				continue
+			}
+		}
+
+		plaintext, err := app.Decryptor.Decrypt(ciphertext)
+		if err != nil {
+			// the key is dropped rather than failing the whole namespace
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[k] = err
+			delete(decrypted, k)
+			continue
+		}
+
+		if cache != nil {
+			cache.values.Store(ciphertext, plaintext)
+		}
+		decrypted[k] = plaintext
+	}
+
+	if decrypted == nil {
+		return items, nil
+	}
+
+	if len(errs) > 0 {
+		return decrypted, &DecryptError{Namespace: namespace, Errs: errs}
+	}
+
+	return decrypted, nil
+}
+
+// newInterpolator creates an interpolator which looks up keys in the items of given namespace and other namespaces.
+// Items of other namespaces are read once per interpolator. A name which is not a known namespace is checked by apollo,
+// so that ${ns:key} does not depend on whether ns has been read before.
+// Placeholders are resolved on every read, so changes of referenced keys are picked up once Watch updates the cache.
+func (app *App) newInterpolator(ctx context.Context, namespace string, items Items, itemsErr error) *interpolator {
+	type loadedItems struct {
+		items Items
+		err   error // a *DecryptError if some keys failed to decrypt
+	}
+	loaded := map[string]loadedItems{normalizeNamespace(namespace): {items: items, err: itemsErr}}
+
+	return &interpolator{
+		lookup: func(namespace, key string) (string, bool, error) {
+			namespace = normalizeNamespace(namespace)
+			l, ok := loaded[namespace]
+			if !ok {
+				items, err := app.getItems(ctx, namespace)
+				var decryptErr *DecryptError
+				if err != nil && !errors.As(err, &decryptErr) {
+					return "", false, err
+				}
+				l = loadedItems{items: items, err: err}
+				loaded[namespace] = l
+			}
+
+			if err := errorOfKey(l.err, key); err != nil {
+				return "", false, err
+			}
+
+			v, ok := l.items[key]
+
+			return v, ok, nil
+		},
+		isNamespace: func(name string) (bool, error) {
+			name = normalizeNamespace(name)
+			if _, ok := loaded[name]; ok {
+				return true, nil
+			}
+
+			exists, err := app.namespaceExists(ctx, name)
+			if err != nil {
+				return false, fmt.Errorf("unresolved namespace %s: %w", name, err)
+			}
+
+			return exists, nil
+		},
+	}
+}
+
+// namespaceExists checks if the namespace exists, a namespace which is neither known nor cached is checked by apollo.
+// A missing namespace is remembered by the negative cache, like reading it does.
+func (app *App) namespaceExists(ctx context.Context, namespace string) (bool, error) {
+	if _, ok := app.releaseKeyMap.Load(namespace); ok {
+		return true, nil
+	}
+	if len(viewItems(app.Cache, namespace)) > 0 {
+		return true, nil
+	}
+	if app.isKnownEmpty(namespace) {
+		return false, nil
+	}
+
+	ns, err := app.getNamespace(ctx, namespace, "")
+	if err != nil {
+		return false, err
+	}
+
+	switch ns.Status {
+	case http.StatusOK, http.StatusNotModified:
+		return true, nil
+	case http.StatusNotFound:
+		app.setKnownEmpty(namespace)
+		return false, nil
+	}
+
+	return false, fmt.Errorf("unexpected status %d", ns.Status)
+}
+
+// GetSubItems gets the items under given prefix in given namespace with the prefix stripped,
+// e.g. db.host becomes host for prefix db. It reads the namespace every time, so it's consistent with the cache.
+func (app *App) GetSubItems(namespace string, prefix string) (Items, error) {
+	items, err := app.GetItemsInNamespace(namespace)
+	var decryptErr *DecryptError
+	if errors.As(err, &decryptErr) {
+		// only the keys under prefix matter
+		err = decryptErr.sub(prefix)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return items.Sub(prefix), err
+}
+
+// GetTree gets the parsed content of json, yaml or xml namespace,
+// the tree is made of map[string]interface{}, []interface{}, json.Number, string, bool and nil.
+func (app *App) GetTree(namespace string) (interface{}, error) {
+	parsed, err := app.getParsedContent(context.Background(), namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneTree(parsed.tree), nil
+}
+
+// GetJSONPath gets the node at given path in the content of json, yaml or xml namespace,
+// path is like $.server.ports[0] or $['server']['ports'][0], the leading $ can be omitted.
+// The node is a map[string]interface{}, []interface{}, json.Number, string, bool or nil.
+func (app *App) GetJSONPath(namespace string, path string) (interface{}, error) {
+	parsed, err := app.getParsedContent(context.Background(), namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := queryPath(parsed.tree, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneTree(node), nil
+}
+
+// getParsedContent parses the content of structured namespace, the result is cached per release key.
+// The content is compared as well, because the release key and the cache are not updated atomically.
+func (app *App) getParsedContent(ctx context.Context, namespace string) (*parsedContent, error) {
+	items, err := app.getRawItems(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.parseItems(namespace, items)
+}
+
+// parseItems parses the raw content of structured namespace, the result is cached per release key.
+// A parse error is cached as well and logged once.
+func (app *App) parseItems(namespace string, items Items) (*parsedContent, error) {
+	namespace = normalizeNamespace(namespace)
+	content := items.Get("content")
+	releaseKey := app.loadReleaseKey(namespace)
+
+	if v, ok := app.parsedMap.Load(namespace); ok {
+		parsed := v.(*parsedContent)
+		if parsed.releaseKey == releaseKey && parsed.content == content {
+			return parsed, parsed.err
+		}
+	}
+
+	parsed, err := parseContent(namespace, content)
+	if err != nil {
+		app.log(LevelWarn, "fail to parse content", app.fields(namespace, F("error", err))...)
+		parsed = &parsedContent{content: content, err: err}
+	} else {
+		parsed.view = parsed.items.Clone()
+		parsed.view["content"] = content
+	}
+
+	parsed.releaseKey = releaseKey
+	app.parsedMap.Store(namespace, parsed)
+
+	return parsed, err
+}
+
+// getRawItems gets the items in given namespace as they are stored in apollo,
+// the content of non-properties namespaces is stored in the key "content".
+// The items are read from cache without copying if possible, so they must not be modified.
+func (app *App) getRawItems(ctx context.Context, namespace string) (Items, error) {
 	// try to get from cache first
-	if items := app.Cache.GetItems(namespace); len(items) > 0 {
+	if items := viewItems(app.Cache, namespace); len(items) > 0 {
+		app.incCounter(MetricCacheHits, 1, app.labels(namespace))
 		return items, nil
 	}
+	app.incCounter(MetricCacheMisses, 1, app.labels(namespace))
+
+	if app.isKnownEmpty(namespace) {
+		return Items{}, nil
+	}
 
-	return app.GetNamespaceFromApollo(namespace)
+	return app.fetchNamespace(ctx, namespace)
+}
+
+// fetchNamespace gets data from apollo on cache miss, concurrent misses of the same namespace share one request.
+// The request is not canceled by any caller, every caller stops waiting when its own ctx is done.
+func (app *App) fetchNamespace(ctx context.Context, namespace string) (Items, error) {
+	return app.flights.Do(ctx, normalizeNamespace(namespace), func(ctx context.Context) (Items, error) {
+		return app.GetNamespaceFromApolloContext(ctx, namespace)
+	})
 }
 
 // GetNamespaceFromApollo gets realtime data in given namespace from apollo and update local cache.
 // This is the most basic method.
 func (app *App) GetNamespaceFromApollo(namespace string) (Items, error) {
+	return app.GetNamespaceFromApolloContext(context.Background(), namespace)
+}
+
+// GetNamespaceFromApolloContext gets realtime data in given namespace from apollo and update local cache,
+// the context carries the parent span and cancels the request.
+func (app *App) GetNamespaceFromApolloContext(ctx context.Context, namespace string) (_ Items, err error) {
 	namespace = normalizeNamespace(namespace) // trim .properties
 
-	ns, err := app.Client.GetNamespace(namespace, app.getReleaseKey(namespace))
+	ctx, span := app.startSpan(ctx, SpanGetNamespaceFromApollo, app.fields(namespace)...)
+	defer func() {
+		span.End(err)
+	}()
+
+	ns, err := app.getNamespace(ctx, namespace, app.getReleaseKey(namespace))
 	if err != nil {
 		return nil, err
 	}
-
-	// only update release key when it's not empty
-	if ns.ReleaseKey != "" {
-		app.releaseKeyMap.Store(namespace, ns.ReleaseKey)
-	}
+	span.SetAttributes(F("status", ns.Status), F("release_key", ns.ReleaseKey))
 
 	// add namespace to notification map with default notification id if not existing,
 	// so that it can be watched in long poll
@@ -156,25 +531,71 @@ func (app *App) GetNamespaceFromApollo(namespace string) (Items, error) {
 
 	// update local cache
 	if len(ns.Items) > 0 {
-		err = app.Cache.SetItems(namespace, ns.Items)
+		app.negativeMap.Delete(namespace)
+		if err = app.Cache.SetItems(namespace, ns.Items); err == nil {
+			app.incCounter(MetricCacheSets, 1, app.labels(namespace))
+			app.setGauge(MetricCacheSize, float64(len(ns.Items)), app.labels(namespace))
+		}
+	} else if ns.Status == http.StatusOK || ns.Status == http.StatusNotFound {
+		// the namespace is empty or missing, rather than not modified
+		app.setKnownEmpty(namespace)
+		if err := app.Cache.Delete(namespace); err == nil {
+			app.incCounter(MetricCacheDeletes, 1, app.labels(namespace))
+			app.setGauge(MetricCacheSize, 0, app.labels(namespace))
+		} else if !os.IsNotExist(err) {
+			app.log(LevelWarn, "fail to delete cache", app.fields(namespace, F("error", err))...)
+		}
+	} else if ns.Status == http.StatusNotModified && app.wasKnownEmpty(namespace) && len(viewItems(app.Cache, namespace)) == 0 {
+		// the release key of an empty namespace is not modified, so it's still empty
+		app.setKnownEmpty(namespace)
+	}
+
+	// only update release key when it's not empty,
+	// it's updated after cache so that a new release key never comes with old items
+	if err == nil && ns.ReleaseKey != "" {
+		app.releaseKeyMap.Store(namespace, ns.ReleaseKey)
 	}
 
 	return ns.Items, err
 }
 
+// getNamespace gets namespace by the client, the context is used if the client implements ApolloContextAPI
+func (app *App) getNamespace(ctx context.Context, namespace string, releaseKey string) (*Namespace, error) {
+	if c, ok := app.Client.(ApolloContextAPI); ok {
+		return c.GetNamespaceContext(ctx, namespace, releaseKey)
+	}
+
+	return app.Client.GetNamespace(namespace, releaseKey)
+}
+
+// getNotifications gets notifications by the client, the context is used if the client implements ApolloContextAPI
+func (app *App) getNotificationsFromApollo(ctx context.Context) (Notifications, error) {
+	if c, ok := app.Client.(ApolloContextAPI); ok {
+		return c.GetNotificationsContext(ctx, app.getNotifications())
+	}
+
+	return app.Client.GetNotifications(app.getNotifications())
+}
+
 // Watch watches changes from apollo using long poll
 func (app *App) Watch(namespaces ...string) (<-chan Notification, <-chan error) {
+	return app.WatchContext(context.Background(), namespaces...)
+}
+
+// WatchContext watches changes from apollo using long poll, it stops when the context is done or Stop is called.
+// Spans of long polls and notifications are children of the span in the context.
+func (app *App) WatchContext(ctx context.Context, namespaces ...string) (<-chan Notification, <-chan error) {
 	namespaces = refineNamespaces(namespaces)
 
 	// get data from apollo and initialize local namespaces data at the beginning
 	for _, namespace := range namespaces {
-		if _, err := app.GetNamespaceFromApollo(namespace); err != nil {
-			app.Logger.Printf("[%s][%s] fail to get data: %s", app.ID, namespace, err.Error())
+		if _, err := app.GetNamespaceFromApolloContext(ctx, namespace); err != nil {
+			app.log(LevelError, "fail to get data", app.fields(namespace, F("error", err))...)
 		}
 	}
 
 	// start long poll in goroutine
-	go app.startLongPoll()
+	go app.startLongPoll(ctx)
 
 	return app.watchChan, app.errChan
 }
@@ -184,6 +605,67 @@ func (app *App) Stop() {
 	app.stopChan <- true
 }
 
+// fields gets the common log fields of app, namespace is omitted if empty
+func (app *App) fields(namespace string, extra ...Field) []Field {
+	fields := []Field{F("app", app.ID), F("cluster", app.Cluster)}
+	if namespace != "" {
+		fields = append(fields, F("namespace", namespace))
+	}
+
+	return append(fields, extra...)
+}
+
+// labels gets the metric labels of app, namespace is omitted if empty.
+// It's nil if metrics are disabled, so that reads do not allocate for nothing.
+func (app *App) labels(namespace string) Labels {
+	if app.Metrics == nil {
+		return nil
+	}
+
+	labels := Labels{"app": app.ID}
+	if namespace != "" {
+		labels["namespace"] = normalizeNamespace(namespace)
+	}
+
+	return labels
+}
+
+// remembers the namespace is empty or missing until negative cache ttl expires
+func (app *App) setKnownEmpty(namespace string) {
+	if app.NegativeCacheTTL > 0 {
+		app.negativeMap.Store(namespace, time.Now().Add(app.NegativeCacheTTL))
+	}
+}
+
+// checks if the namespace is known to be empty or missing
+func (app *App) isKnownEmpty(namespace string) bool {
+	namespace = normalizeNamespace(namespace)
+
+	v, ok := app.negativeMap.Load(namespace)
+	if !ok {
+		return false
+	}
+
+	// the expired entry is kept, so that a not modified response can tell the namespace is still empty
+	return time.Now().Before(v.(time.Time))
+}
+
+// checks if the namespace has been known to be empty or missing, even if the negative cache has expired
+func (app *App) wasKnownEmpty(namespace string) bool {
+	_, ok := app.negativeMap.Load(normalizeNamespace(namespace))
+
+	return ok
+}
+
+// loads release key of given namespace, it's empty if unknown
+func (app *App) loadReleaseKey(namespace string) string {
+	if v, ok := app.releaseKeyMap.Load(namespace); ok {
+		return v.(string)
+	}
+
+	return ""
+}
+
 // gets release key of given namespace
 func (app *App) getReleaseKey(namespace string) string {
 	if m, ok := app.releaseKeyMap.Load(namespace); ok {
@@ -195,7 +677,7 @@ func (app *App) getReleaseKey(namespace string) string {
 	return ""
 }
 
-func (app *App) startLongPoll() {
+func (app *App) startLongPoll(ctx context.Context) {
 	timer := time.NewTimer(app.LongPollInterval)
 	defer timer.Stop()
 
@@ -203,30 +685,70 @@ func (app *App) startLongPoll() {
 		// wait for returns from channel
 		select {
 		case <-timer.C:
-			app.longPoll()
+			app.longPoll(ctx)
 			timer.Reset(app.LongPollInterval)
 		case <-app.stopChan:
-			app.Logger.Printf("[%s] stop watching", app.ID)
+			app.log(LevelInfo, "stop watching", app.fields("")...)
+			return
+		case <-ctx.Done():
+			app.log(LevelInfo, "stop watching", app.fields("", F("error", ctx.Err()))...)
 			return
 		}
 	}
 }
 
-func (app *App) longPoll() {
-	if notifications, err := app.Client.GetNotifications(app.getNotifications()); err == nil {
-		// notifications will be empty if no changes
-		for _, notification := range notifications {
-			// update notification id and then fetch latest data from apollo
-			app.notificationMap.Store(notification.Namespace, notification.NotificationID)
-			if _, err := app.GetNamespaceFromApollo(notification.Namespace); err != nil {
-				app.Logger.Printf("[%s][%s] fail to get data: %s", app.ID, notification.Namespace, err.Error())
-			}
+func (app *App) longPoll(ctx context.Context) {
+	app.incCounter(MetricLongPolls, 1, app.labels(""))
+
+	ctx, span := app.startSpan(ctx, SpanLongPoll, app.fields("")...)
+
+	notifications, err := app.getNotificationsFromApollo(ctx)
+	if err != nil {
+		span.End(err)
+
+		failures := atomic.AddInt64(&app.pollFailures, 1)
+		app.incCounter(MetricLongPollFailures, 1, app.labels(""))
+		app.setGauge(MetricLongPollConsecutiveFails, float64(failures), app.labels(""))
 
-			app.watchChan <- notification
+		app.log(LevelError, "fail to fetch notifications", app.fields("", F("error", err), F("failures", failures))...)
+		select {
+		case app.errChan <- err:
+		case <-ctx.Done():
 		}
-	} else {
-		app.Logger.Printf("[%s] fail to fetch notifications: %s", app.ID, err.Error())
-		app.errChan <- err
+		return
+	}
+
+	atomic.StoreInt64(&app.pollFailures, 0)
+	app.setGauge(MetricLongPollConsecutiveFails, 0, app.labels(""))
+	span.SetAttributes(F("notifications", len(notifications)))
+
+	// notifications will be empty if no changes
+	for _, notification := range notifications {
+		app.incCounter(MetricNotifications, 1, app.labels(notification.Namespace))
+
+		// update notification id and then fetch latest data from apollo
+		app.notificationMap.Store(notification.Namespace, notification.NotificationID)
+		app.negativeMap.Delete(notification.Namespace)
+		if _, err := app.GetNamespaceFromApolloContext(ctx, notification.Namespace); err != nil {
+			app.log(LevelError, "fail to get data", app.fields(notification.Namespace, F("error", err))...)
+		}
+
+		app.notify(ctx, notification)
+	}
+
+	span.End(nil)
+}
+
+// notify dispatches notification to the watcher
+func (app *App) notify(ctx context.Context, notification Notification) {
+	ctx, span := app.startSpan(ctx, SpanNotify,
+		app.fields(notification.Namespace, F("notification_id", notification.NotificationID))...)
+
+	select {
+	case app.watchChan <- notification:
+		span.End(nil)
+	case <-ctx.Done():
+		span.End(ctx.Err())
 	}
 }
 
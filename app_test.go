@@ -1,10 +1,16 @@
 package lunar
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -83,6 +89,12 @@ func (ts *LunarTestSuite) TestGetItems() {
 
 	should.NoError(err)
 	should.Contains(items, "portal.elastic.document.type")
+
+	// the items returned are copies
+	items["portal.elastic.document.type"] = "changed"
+	v, err := ts.app.GetValue("portal.elastic.document.type")
+	should.NoError(err)
+	should.Equal("biz", v)
 }
 
 func (ts *LunarTestSuite) TestGetContent() {
@@ -109,6 +121,380 @@ func (ts *LunarTestSuite) TestGetContent() {
 	should.Contains(m, defaultNamespace)
 }
 
+func (ts *LunarTestSuite) TestGetSubItems() {
+	should := require.New(ts.T())
+
+	ts.mockGetNamespace(defaultNamespace, "")
+	items, err := ts.app.GetSubItems(defaultNamespace, "portal.elastic")
+
+	should.NoError(err)
+	should.Equal(Items{"document.type": "biz", "cluster.name": "hermes-es-fws"}, items)
+
+	ts.mockGetNamespace("b.yaml", "")
+	items, err = ts.app.GetSubItems("b.yaml", "server")
+
+	should.NoError(err)
+	should.Equal(Items{"host": "localhost", "ports[0]": "80", "ports[1]": "443"}, items)
+}
+
+func (ts *LunarTestSuite) TestGetContentMemoized() {
+	should := require.New(ts.T())
+
+	app := New("MemoApp")
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"a.b": "1"}))
+	app.releaseKeyMap.Store(defaultNamespace, "20230101-1")
+
+	content, err := app.GetContent(defaultNamespace)
+	should.NoError(err)
+	should.Equal(`{"a":{"b":"1"}}`, content)
+
+	v, ok := app.contentMap.Load(defaultNamespace)
+	should.True(ok)
+	memo := v.(*memoContent)
+
+	// same release key and items reuse the memo
+	content, err = app.GetContent(defaultNamespace)
+	should.NoError(err)
+	should.Equal(`{"a":{"b":"1"}}`, content)
+	v, _ = app.contentMap.Load(defaultNamespace)
+	should.Same(memo, v)
+
+	// items changed by others are not hidden by the memo
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"a.b": "2"}))
+	content, err = app.GetContent(defaultNamespace)
+	should.NoError(err)
+	should.Equal(`{"a":{"b":"2"}}`, content)
+
+	// new release
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"a.c": "3"}))
+	app.releaseKeyMap.Store(defaultNamespace, "20230101-2")
+	content, err = app.GetContent(defaultNamespace)
+	should.NoError(err)
+	should.Equal(`{"a":{"c":"3"}}`, content)
+}
+
+func (ts *LunarTestSuite) TestInterpolation() {
+	should := require.New(ts.T())
+
+	app := New("InterpolationApp", WithInterpolation(true), WithNegativeCacheTTL(time.Minute))
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{
+		"db.host": "localhost",
+		"db.url":  "mysql://${db.host}:${db.port:3306}",
+		"db.user": "${common:user}",
+		"db.pass": "${secret:pass}",
+		"loop":    "${loop}",
+	}))
+	should.NoError(app.Cache.SetItems("common", Items{"user": "${name}", "name": "root"}))
+
+	// db.port is not a namespace, it's checked once and remembered by the negative cache
+	gock.New(app.Server).
+		Get("/configs/InterpolationApp/default/db.port").
+		Reply(http.StatusNotFound)
+	for i := 0; i < 2; i++ {
+		v, err := app.GetValue("db.url")
+		should.NoError(err)
+		should.Equal("mysql://localhost:3306", v)
+	}
+
+	v, err := app.GetValue("db.user")
+	should.NoError(err)
+	should.Equal("root", v)
+
+	// a namespace which has not been read is fetched on demand
+	gock.New(app.Server).
+		Get("/configs/InterpolationApp/default/secret").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"InterpolationApp","cluster":"default","namespaceName":"secret","configurations":{"pass":"123"},"releaseKey":"1"}`)
+	v, err = app.GetValue("db.pass")
+	should.NoError(err)
+	should.Equal("123", v)
+
+	// a namespace which can not be checked is an error rather than a default
+	app2 := New("InterpolationApp2", WithInterpolation(true))
+	should.NoError(app2.Cache.SetItems(defaultNamespace, Items{"db.host": "${other:db.host}"}))
+	gock.New(app2.Server).
+		Get("/configs/InterpolationApp2/default/other").
+		ReplyError(errors.New("connection refused"))
+	_, err = app2.GetValue("db.host")
+	should.Error(err)
+	should.Contains(err.Error(), "unresolved namespace other")
+
+	_, err = app.GetValue("loop")
+	should.EqualError(err, "placeholder cycle: application:loop -> application:loop")
+
+	_, err = app.GetItems()
+	should.Error(err)
+
+	items, err := app.GetItemsInNamespace("common")
+	should.NoError(err)
+	should.Equal(Items{"user": "root", "name": "root"}, items)
+
+	// referenced keys are resolved again once they are changed
+	should.NoError(app.Cache.SetItems("common", Items{"user": "admin"}))
+	v, err = app.GetValue("db.user")
+	should.NoError(err)
+	should.Equal("admin", v)
+
+	// the content is not resolved
+	content, err := app.GetContent("common")
+	should.NoError(err)
+	should.Equal(`{"user":"admin"}`, content)
+}
+
+func (ts *LunarTestSuite) TestDecryption() {
+	should := require.New(ts.T())
+
+	d, err := NewAESGCMDecryptor([]byte("0123456789abcdef"))
+	should.NoError(err)
+	password, err := d.Encrypt("s3cr3t")
+	should.NoError(err)
+
+	decrypts := 0
+	decryptor := DecryptorFunc(func(ciphertext string) (string, error) {
+		decrypts++
+		return d.Decrypt(ciphertext)
+	})
+
+	var logs []string
+	logger := LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+
+	folder := ts.T().TempDir()
+	app := New("DecryptionApp", WithDecryptor(decryptor), WithLogger(logger)).
+		UseCache(NewFileCache("DecryptionApp", folder))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"appId":          "DecryptionApp",
+		"cluster":        "default",
+		"namespaceName":  defaultNamespace,
+		"configurations": map[string]string{"db.user": "root", "db.password": password},
+		"releaseKey":     "1",
+	})
+	gock.New(app.Server).
+		Get("/configs/DecryptionApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(string(body))
+
+	for i := 0; i < 3; i++ {
+		items, err := app.GetItems()
+		should.NoError(err)
+		should.Equal(Items{"db.user": "root", "db.password": "s3cr3t"}, items)
+	}
+	should.Equal(1, decrypts)
+
+	// the cache keeps the encrypted value
+	should.Equal(password, app.Cache.GetItems(defaultNamespace).Get("db.password"))
+	b, err := os.ReadFile(filepath.Join(folder, "DecryptionApp", defaultNamespace))
+	should.NoError(err)
+	should.NotContains(string(b), "s3cr3t")
+	for _, line := range logs {
+		should.NotContains(line, "s3cr3t")
+	}
+
+	// a new release decrypts again
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"db.password": password}))
+	app.releaseKeyMap.Store(defaultNamespace, "2")
+	v, err := app.GetValue("db.password")
+	should.NoError(err)
+	should.Equal("s3cr3t", v)
+	should.Equal(2, decrypts)
+
+	// a key failed to decrypt does not affect the others
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"db.password": "ENC(YWJj)", "db.user": "root", "a": "1"}))
+	_, err = app.GetValue("db.password")
+	should.EqualError(err, "fail to decrypt key db.password in namespace application: ciphertext is too short")
+	v, err = app.GetValue("db.user")
+	should.NoError(err)
+	should.Equal("root", v)
+
+	items, err := app.GetItems()
+	var decryptErr *DecryptError
+	should.ErrorAs(err, &decryptErr)
+	should.Contains(decryptErr.Errs, "db.password")
+	should.Equal(Items{"db.user": "root", "a": "1"}, items)
+
+	sub, err := app.GetSubItems(defaultNamespace, "db")
+	should.ErrorAs(err, &decryptErr)
+	should.Equal(Items{"user": "root"}, sub)
+	sub, err = app.GetSubItems(defaultNamespace, "a")
+	should.NoError(err)
+	should.Empty(sub)
+
+	// placeholders referring to other keys are resolved
+	app.Interpolation = true
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"a": "ENC(YWJj)", "b": "${c}", "c": "1", "d": "${a}"}))
+	v, err = app.GetValue("b")
+	should.NoError(err)
+	should.Equal("1", v)
+	_, err = app.GetValue("d")
+	should.ErrorContains(err, "fail to decrypt key a")
+	app.Interpolation = false
+
+	should.NoError(app.Cache.SetItems(defaultNamespace, Items{"a": "ENC(YWJj)", "b": "ENC(YWJk)"}))
+	_, err = app.GetItems()
+	should.EqualError(err, "fail to decrypt keys a, b in namespace application")
+}
+
+func (ts *LunarTestSuite) TestMetrics() {
+	should := require.New(ts.T())
+
+	m := newTestMetrics()
+	app := New("MetricsApp", WithMetrics(m))
+
+	gock.New(app.Server).
+		Get("/configs/MetricsApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"MetricsApp","namespaceName":"application","configurations":{"a":"1","b":"2"},"releaseKey":"1"}`)
+
+	for i := 0; i < 3; i++ {
+		_, err := app.GetItems()
+		should.NoError(err)
+	}
+
+	ns := Labels{"app": "MetricsApp", "namespace": defaultNamespace}
+	should.Equal(float64(2), m.counters[metricKey(MetricCacheHits, ns)])
+	should.Equal(float64(1), m.counters[metricKey(MetricCacheMisses, ns)])
+	should.Equal(float64(1), m.counters[metricKey(MetricCacheSets, ns)])
+	should.Equal(float64(2), m.gauges[metricKey(MetricCacheSize, ns)])
+
+	request := Labels{"app": "MetricsApp", "endpoint": "configs", "status": "200"}
+	should.Equal(float64(1), m.counters[metricKey(MetricRequests, request)])
+	should.Len(m.histograms[metricKey(MetricRequestDuration, request)], 1)
+
+	// long polls
+	go func() {
+		for {
+			select {
+			case <-app.watchChan:
+			case <-app.errChan:
+			}
+		}
+	}()
+
+	gock.New(app.Server).
+		Get("/notifications/v2").
+		ReplyError(errors.New("connection refused"))
+	gock.New(app.Server).
+		Get("/notifications/v2").
+		ReplyError(errors.New("connection refused"))
+	app.longPoll(context.Background())
+	app.longPoll(context.Background())
+
+	labels := Labels{"app": "MetricsApp"}
+	should.Equal(float64(2), m.counters[metricKey(MetricRequests, Labels{"app": "MetricsApp", "endpoint": "notifications", "status": "error"})])
+	should.Equal(float64(2), m.counters[metricKey(MetricLongPollFailures, labels)])
+	should.Equal(float64(2), m.gauges[metricKey(MetricLongPollConsecutiveFails, labels)])
+
+	gock.New(app.Server).
+		Get("/notifications/v2").
+		Reply(http.StatusOK).
+		BodyString(`[{"namespaceName":"application","notificationId":2}]`)
+	gock.New(app.Server).
+		Get("/configs/MetricsApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"MetricsApp","namespaceName":"application","configurations":{"a":"1"},"releaseKey":"2"}`)
+	app.longPoll(context.Background())
+
+	should.Equal(float64(3), m.counters[metricKey(MetricLongPolls, labels)])
+	should.Equal(float64(0), m.gauges[metricKey(MetricLongPollConsecutiveFails, labels)])
+	should.Equal(float64(1), m.counters[metricKey(MetricNotifications, ns)])
+	should.Equal(float64(1), m.gauges[metricKey(MetricCacheSize, ns)])
+}
+
+func (ts *LunarTestSuite) TestGetYAMLItems() {
+	should := require.New(ts.T())
+
+	ns := "b.yaml"
+
+	ts.mockGetNamespace(ns, "")
+	items, err := ts.app.GetItemsInNamespace(ns)
+
+	should.NoError(err)
+	should.Equal(Items{
+		"content":         "server:\n  host: localhost\n  ports:\n    - 80\n    - 443\n",
+		"server.host":     "localhost",
+		"server.ports[0]": "80",
+		"server.ports[1]": "443",
+	}, items)
+
+	v, err := ts.app.GetValueInNamespace("server.ports[1]", ns)
+	should.NoError(err)
+	should.Equal("443", v)
+
+	content, err := ts.app.GetContent(ns)
+	should.NoError(err)
+	should.Equal("server:\n  host: localhost\n  ports:\n    - 80\n    - 443\n", content)
+}
+
+func (ts *LunarTestSuite) TestGetJSONItems() {
+	should := require.New(ts.T())
+
+	ns := "c.json"
+
+	ts.mockGetNamespace(ns, "")
+	items, err := ts.app.GetItemsInNamespace(ns)
+
+	should.NoError(err)
+	should.Equal("localhost", items.Get("server.host"))
+	should.Equal("443", items.Get("server.ports[1]"))
+	should.Equal("true", items.Get("server.tls"))
+	should.Contains(items.Get("content"), `"tls": true`)
+
+	parsed, ok := ts.app.parsedMap.Load(ns)
+	should.True(ok)
+
+	v, err := ts.app.GetJSONPath(ns, "$.server.ports[1]")
+	should.NoError(err)
+	should.Equal(json.Number("443"), v)
+
+	_, err = ts.app.GetJSONPath(ns, "$.server.ports[2]")
+	should.Error(err)
+
+	// parsed result is reused
+	again, _ := ts.app.parsedMap.Load(ns)
+	should.Same(parsed, again)
+
+	// the raw content is still readable if it can not be parsed
+	should.NoError(ts.app.Cache.SetItems("broken.json", Items{"content": `{"a": `}))
+	items, err = ts.app.GetItemsInNamespace("broken.json")
+	should.NoError(err)
+	should.Equal(Items{"content": `{"a": `}, items)
+	_, err = ts.app.GetTree("broken.json")
+	should.Error(err)
+}
+
+func (ts *LunarTestSuite) TestGetXMLItems() {
+	should := require.New(ts.T())
+
+	ns := "d.xml"
+
+	ts.mockGetNamespace(ns, "")
+	v, err := ts.app.GetValueInNamespace("config.db.port", ns)
+
+	should.NoError(err)
+	should.Equal("3306", v)
+
+	tree, err := ts.app.GetTree(ns)
+	should.NoError(err)
+	should.Equal(map[string]interface{}{
+		"config": map[string]interface{}{
+			"db": map[string]interface{}{
+				"@host": "localhost",
+				"port":  "3306",
+			},
+		},
+	}, tree)
+
+	v2, err := ts.app.GetJSONPath(ns, "$.config.db['@host']")
+	should.NoError(err)
+	should.Equal("localhost", v2)
+
+	_, err = ts.app.GetTree("a.txt")
+	should.Error(err)
+}
+
 func (ts *LunarTestSuite) TestWatch() {
 	should := require.New(ts.T())
 
@@ -141,3 +527,143 @@ stopped:
 	should.NoError(err)
 	should.Equal("version 2", content)
 }
+
+func (ts *LunarTestSuite) TestUseSharedCache() {
+	should := require.New(ts.T())
+
+	shared := new(MemoryCache)
+
+	app1 := New("app1").UseCache(shared)
+	app2 := New("app2").UseCache(shared)
+
+	should.NoError(app1.Cache.SetItems(defaultNamespace, Items{"foo": "1"}))
+	should.NoError(app2.Cache.SetItems(defaultNamespace, Items{"foo": "2"}))
+
+	v, err := app1.GetValue("foo")
+	should.NoError(err)
+	should.Equal("1", v)
+
+	v, err = app2.GetValue("foo")
+	should.NoError(err)
+	should.Equal("2", v)
+
+	should.Len(shared.GetKeys(), 2)
+
+	stats := app1.CacheStats()
+	should.EqualValues(1, stats.Hits)
+	should.EqualValues(1, stats.Sets)
+	should.Equal(1, stats.Namespaces[defaultNamespace].Size)
+
+	app3 := New("app3").UseCache(struct{ Cache }{shared})
+	should.Empty(app3.CacheStats().Namespaces)
+}
+
+func (ts *LunarTestSuite) TestGetItemsConcurrently() {
+	should := require.New(ts.T())
+
+	app := New("ConcurrentApp")
+
+	resBody, _ := os.ReadFile("./mocks/GetNamespace_application.json")
+
+	var calls int32
+	res := gock.New(app.Server).
+		Get("/configs/ConcurrentApp/default/application").
+		Persist().
+		Filter(func(*http.Request) bool {
+			atomic.AddInt32(&calls, 1)
+			return true
+		}).
+		Reply(http.StatusOK).
+		Delay(100 * time.Millisecond).
+		BodyString(string(resBody))
+	defer gock.Remove(res.Mock)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := app.GetValue("portal.elastic.document.type")
+			ts.NoError(err)
+			ts.Equal("biz", v)
+		}()
+	}
+	wg.Wait()
+
+	should.EqualValues(1, atomic.LoadInt32(&calls))
+}
+
+func (ts *LunarTestSuite) TestNegativeCache() {
+	should := require.New(ts.T())
+
+	app := New("NegativeApp", WithNegativeCacheTTL(200*time.Millisecond))
+
+	// gock mocks only match once, so a second request would fail
+	gock.New(app.Server).
+		Get("/configs/NegativeApp/default/empty").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"NegativeApp","cluster":"default","namespaceName":"empty","configurations":{},"releaseKey":"1"}`)
+	gock.New(app.Server).
+		Get("/configs/NegativeApp/default/missing").
+		Reply(http.StatusNotFound)
+
+	for i := 0; i < 3; i++ {
+		items, err := app.GetItemsInNamespace("empty")
+		should.NoError(err)
+		should.Len(items, 0)
+
+		content, err := app.GetContent("missing")
+		should.NoError(err)
+		should.Equal("{}", content)
+	}
+
+	// watch notification invalidates negative cache
+	gock.New(app.Server).
+		Get("/notifications/v2").
+		Reply(http.StatusOK).
+		BodyString(`[{"namespaceName":"empty","notificationId":2}]`)
+	gock.New(app.Server).
+		Get("/configs/NegativeApp/default/empty").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"NegativeApp","cluster":"default","namespaceName":"empty","configurations":{"foo":"bar"},"releaseKey":"2"}`)
+
+	go func() { <-app.watchChan }()
+	app.longPoll(context.Background())
+
+	v, err := app.GetValueInNamespace("foo", "empty")
+	should.NoError(err)
+	should.Equal("bar", v)
+
+	// expired
+	time.Sleep(200 * time.Millisecond)
+
+	gock.New(app.Server).
+		Get("/configs/NegativeApp/default/missing").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"NegativeApp","cluster":"default","namespaceName":"missing","configurations":{"foo":"baz"},"releaseKey":"1"}`)
+
+	v, err = app.GetValueInNamespace("foo", "missing")
+	should.NoError(err)
+	should.Equal("baz", v)
+
+	// an empty namespace which is not modified after expiry is still known empty
+	gock.New(app.Server).
+		Get("/configs/NegativeApp/default/empty2").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"NegativeApp","cluster":"default","namespaceName":"empty2","configurations":{},"releaseKey":"1"}`)
+	_, err = app.GetItemsInNamespace("empty2")
+	should.NoError(err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	notModified := gock.New(app.Server).
+		Get("/configs/NegativeApp/default/empty2").
+		MatchParam("releaseKey", "1")
+	notModified.Reply(http.StatusNotModified)
+	for i := 0; i < 3; i++ {
+		items, err := app.GetItemsInNamespace("empty2")
+		should.NoError(err)
+		should.Len(items, 0)
+	}
+	should.True(notModified.Mock.Done())
+}
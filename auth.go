@@ -0,0 +1,293 @@
+package lunar
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// tokenExpiryDelta is how long before expiry a token is refreshed
+	tokenExpiryDelta = 10 * time.Second
+	// clockSkewThreshold is the minimum skew to be corrected, apollo rejects timestamps deviating more than a minute
+	clockSkewThreshold = 30 * time.Second
+)
+
+// Authenticator adds credentials to requests to apollo, e.g. access key signature, bearer token or basic auth
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc is a bridge between Authenticator and a function
+type AuthenticatorFunc func(*http.Request) error
+
+// Authenticate implements Authenticator interface
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error { return f(req) }
+
+// UnauthorizedHandler can be implemented by authenticators to handle 401 responses, e.g. to refresh the token.
+// If it returns true, the request is authenticated and sent again, only once.
+type UnauthorizedHandler interface {
+	Unauthorized(resp *http.Response) bool
+}
+
+// AccessKeyAuthenticator signs requests with apollo access key,
+// the path with query of request url is signed by HMAC-SHA1 and set to Authorization and Timestamp headers.
+// The path is relative to the server, so ServerPath, the path of server url like /apollo, is stripped before signing.
+// If apollo responds 401 with a Date header far from local time, the clock skew is applied to later timestamps.
+type AccessKeyAuthenticator struct {
+	skew       int64 // server time minus local time in nanoseconds, the first field to be 64-bit aligned for atomic
+	AppID      string
+	Secret     string
+	ServerPath string
+}
+
+// make sure AccessKeyAuthenticator implements Authenticator, UnauthorizedHandler and ClockSkewProvider
+var _ Authenticator = new(AccessKeyAuthenticator)
+var _ UnauthorizedHandler = new(AccessKeyAuthenticator)
+var _ ClockSkewProvider = new(AccessKeyAuthenticator)
+
+// NewAccessKeyAuthenticator creates an access key authenticator
+func NewAccessKeyAuthenticator(appID, secret string) *AccessKeyAuthenticator {
+	return &AccessKeyAuthenticator{AppID: appID, Secret: secret}
+}
+
+// serverPath gets the escaped path of server url, e.g. /apollo for http://localhost:8080/apollo
+func serverPath(server string) string {
+	u, err := url.Parse(server)
+	if err != nil {
+		return ""
+	}
+
+	return u.EscapedPath()
+}
+
+// Authenticate implements Authenticator interface
+func (a *AccessKeyAuthenticator) Authenticate(req *http.Request) error {
+	now := time.Now().Add(a.ClockSkew())
+	pathWithQuery := strings.TrimPrefix(req.URL.RequestURI(), a.ServerPath)
+	for k, v := range buildHeaders(pathWithQuery, a.AppID, a.Secret, now) {
+		req.Header.Set(k, v)
+	}
+
+	return nil
+}
+
+// Unauthorized estimates the server time by the Date header of response, if the skew from the local time
+// is large enough to be the cause of 401, it's applied to later timestamps and the request is sent again
+func (a *AccessKeyAuthenticator) Unauthorized(resp *http.Response) bool {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return false
+	}
+
+	skew := time.Until(date)
+	if diff := skew - a.ClockSkew(); diff > -clockSkewThreshold && diff < clockSkewThreshold {
+		return false
+	}
+
+	atomic.StoreInt64(&a.skew, int64(skew))
+
+	return true
+}
+
+// ClockSkew gets the measured server time minus local time, 0 if no skew is detected
+func (a *AccessKeyAuthenticator) ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&a.skew))
+}
+
+// ClockSkewProvider is implemented by clients and authenticators which measure the clock skew of apollo server
+type ClockSkewProvider interface {
+	ClockSkew() time.Duration
+}
+
+// BearerToken sets a static token to Authorization header as Bearer token,
+// use BearerTokenHeader with access key signing which sets Authorization header as well
+func BearerToken(token string) Authenticator {
+	return BearerTokenHeader("Authorization", token)
+}
+
+// BearerTokenHeader sets a static token to given header as Bearer token, e.g. for a gateway in front of apollo
+func BearerTokenHeader(header, token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set(header, "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth sets username and password to Authorization header,
+// use BasicAuthHeader with access key signing which sets Authorization header as well
+func BasicAuth(username, password string) Authenticator {
+	return BasicAuthHeader("Authorization", username, password)
+}
+
+// BasicAuthHeader sets username and password to given header, e.g. Proxy-Authorization for a proxy in front of apollo
+func BasicAuthHeader(header, username, password string) Authenticator {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set(header, "Basic "+credentials)
+		return nil
+	})
+}
+
+// TokenSource gets tokens, e.g. from an OAuth2 server, zero expiry means the token does not expire
+type TokenSource interface {
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+// TokenSourceFunc is a bridge between TokenSource and a function
+type TokenSourceFunc func(ctx context.Context) (string, time.Time, error)
+
+// Token implements TokenSource interface
+func (f TokenSourceFunc) Token(ctx context.Context) (string, time.Time, error) { return f(ctx) }
+
+// TokenAuthenticator sets Bearer token from token source to the header, the token is cached
+// and refreshed when it's about to expire or it's rejected by a 401 response
+type TokenAuthenticator struct {
+	Source TokenSource
+	Header string // the header of token, default is Authorization
+	// IsRejected checks if a 401 response rejects the token rather than other credentials like access key,
+	// default is to check the Bearer challenge in WWW-Authenticate header, which is required by RFC 6750
+	IsRejected func(resp *http.Response) bool
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// make sure TokenAuthenticator implements Authenticator and UnauthorizedHandler
+var _ Authenticator = new(TokenAuthenticator)
+var _ UnauthorizedHandler = new(TokenAuthenticator)
+
+// NewTokenAuthenticator creates a token authenticator which sets Authorization header
+func NewTokenAuthenticator(source TokenSource) *TokenAuthenticator {
+	return &TokenAuthenticator{Source: source, Header: "Authorization"}
+}
+
+// Authenticate implements Authenticator interface
+func (a *TokenAuthenticator) Authenticate(req *http.Request) error {
+	token, err := a.getToken(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(a.header(), "Bearer "+token)
+
+	return nil
+}
+
+func (a *TokenAuthenticator) header() string {
+	if a.Header == "" {
+		return "Authorization"
+	}
+
+	return a.Header
+}
+
+// getToken gets the cached token or a new one from source
+func (a *TokenAuthenticator) getToken(ctx context.Context) (string, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.token != "" && (a.expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(a.expiry)) {
+		return a.token, nil
+	}
+
+	token, expiry, err := a.Source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", errors.New("empty token")
+	}
+
+	a.token, a.expiry = token, expiry
+
+	return token, nil
+}
+
+// Unauthorized drops the cached token if it's rejected, so the request is sent again with a new token.
+// A 401 response caused by other credentials is ignored, and so is a token which has been refreshed already.
+func (a *TokenAuthenticator) Unauthorized(resp *http.Response) bool {
+	isRejected := a.IsRejected
+	if isRejected == nil {
+		isRejected = hasBearerChallenge
+	}
+	if !isRejected(resp) {
+		return false
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if resp.Request == nil || resp.Request.Header.Get(a.header()) == "Bearer "+a.token {
+		a.token = ""
+	}
+
+	return true
+}
+
+// hasBearerChallenge checks if the WWW-Authenticate header of response asks for a Bearer token
+func hasBearerChallenge(resp *http.Response) bool {
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		// a header may have multiple challenges like: Basic realm="a", Bearer realm="b", error="invalid_token"
+		for _, part := range strings.Split(header, ",") {
+			if fields := strings.Fields(part); len(fields) > 0 && strings.EqualFold(fields[0], "Bearer") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// chainAuthenticator runs authenticators in order
+type chainAuthenticator []Authenticator
+
+// ChainAuthenticators combines authenticators, they authenticate requests in order,
+// so they should set different headers, e.g. access key for apollo and token for a gateway in front of it
+func ChainAuthenticators(auths ...Authenticator) Authenticator {
+	var chain chainAuthenticator
+	for _, a := range auths {
+		if c, ok := a.(chainAuthenticator); ok {
+			chain = append(chain, c...)
+		} else if a != nil {
+			chain = append(chain, a)
+		}
+	}
+
+	if len(chain) == 1 {
+		return chain[0]
+	}
+
+	return chain
+}
+
+// Authenticate implements Authenticator interface
+func (c chainAuthenticator) Authenticate(req *http.Request) error {
+	for _, a := range c {
+		if err := a.Authenticate(req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unauthorized implements UnauthorizedHandler interface, it returns true if any authenticator returns true
+func (c chainAuthenticator) Unauthorized(resp *http.Response) bool {
+	retry := false
+	for _, a := range c {
+		if h, ok := a.(UnauthorizedHandler); ok && h.Unauthorized(resp) {
+			retry = true
+		}
+	}
+
+	return retry
+}
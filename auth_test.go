@@ -0,0 +1,315 @@
+package lunar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNamespaceBody = `{"appId":"myApp","namespaceName":"application","configurations":{"a":"1"}}`
+
+func TestChainAuthenticators(t *testing.T) {
+	should := require.New(t)
+
+	secret := "12848b38781e4daf9d05054580282a8e"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the gateway
+		if r.Header.Get("X-Gateway-Authorization") != "Bearer gateway-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// apollo
+		expected := fmt.Sprintf(AuthorizationFormat, "myApp", sign(r.Header.Get("Timestamp"), r.URL.RequestURI(), secret))
+		if r.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testNamespaceBody))
+	}))
+	defer server.Close()
+
+	gateway := NewTokenAuthenticator(TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		return "gateway-token", time.Time{}, nil
+	}))
+	gateway.Header = "X-Gateway-Authorization"
+
+	client := NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithAccessKeySecret(secret),
+		WithAuthenticator(gateway),
+	)
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+	should.Equal("1", ns.Items.Get("a"))
+
+	// without gateway token
+	client = NewApolloClient("myApp", WithServer(server.URL), WithAccessKeySecret(secret))
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusForbidden, ns.Status)
+
+	// wrong secret
+	client = NewApolloClient("myApp", WithServer(server.URL), WithAccessKeySecret("wrong"), WithAuthenticator(gateway))
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusUnauthorized, ns.Status)
+
+	// nested chains are flattened and nil is skipped
+	chain := ChainAuthenticators(ChainAuthenticators(BasicAuth("user", "pass"), nil), BearerToken("token"))
+	should.Len(chain, 2)
+	should.IsType(AuthenticatorFunc(nil), ChainAuthenticators(nil, BasicAuth("user", "pass")))
+}
+
+func TestAccessKeyServerPath(t *testing.T) {
+	should := require.New(t)
+
+	secret := "12848b38781e4daf9d05054580282a8e"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// apollo behind a proxy which strips the /apollo prefix
+		pathWithQuery := strings.TrimPrefix(r.URL.RequestURI(), "/apollo")
+		pathWithQuery = strings.TrimPrefix(pathWithQuery, "/")
+		expected := fmt.Sprintf(AuthorizationFormat, "myApp", sign(r.Header.Get("Timestamp"), "/"+pathWithQuery, secret))
+		if r.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testNamespaceBody))
+	}))
+	defer server.Close()
+
+	// the path relative to the server is signed
+	for _, url := range []string{server.URL + "/apollo", server.URL + "/apollo/", server.URL, server.URL + "/"} {
+		client := NewApolloClient("myApp", WithServer(url), WithAccessKeySecret(secret))
+		ns, err := client.GetNamespace(defaultNamespace, "")
+		should.NoError(err)
+		should.Equal(http.StatusOK, ns.Status, url)
+	}
+
+	should.Equal("/apollo", serverPath("http://localhost:8080/apollo"))
+	should.Equal("", serverPath("http://localhost:8080"))
+}
+
+func TestBasicAuthAndBearerToken(t *testing.T) {
+	should := require.New(t)
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/configs", nil)
+	should.NoError(err)
+
+	should.NoError(BasicAuth("user", "pass").Authenticate(req))
+	username, password, ok := req.BasicAuth()
+	should.True(ok)
+	should.Equal("user", username)
+	should.Equal("pass", password)
+
+	should.NoError(BearerToken("token").Authenticate(req))
+	should.Equal("Bearer token", req.Header.Get("Authorization"))
+
+	should.NoError(BasicAuthHeader("Proxy-Authorization", "user", "pass").Authenticate(req))
+	should.Equal("Basic dXNlcjpwYXNz", req.Header.Get("Proxy-Authorization"))
+	should.NoError(BearerTokenHeader("X-Gateway-Authorization", "token").Authenticate(req))
+	should.Equal("Bearer token", req.Header.Get("X-Gateway-Authorization"))
+}
+
+func TestStaticAuthWithAccessKey(t *testing.T) {
+	should := require.New(t)
+
+	secret := "12848b38781e4daf9d05054580282a8e"
+	var accessKeyFailures int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Gateway-Authorization") != "Bearer gateway-token" ||
+			r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		expected := fmt.Sprintf(AuthorizationFormat, "myApp", sign(r.Header.Get("Timestamp"), r.URL.RequestURI(), secret))
+		if r.Header.Get("Authorization") != expected {
+			atomic.AddInt64(&accessKeyFailures, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testNamespaceBody))
+	}))
+	defer server.Close()
+
+	// both the access key signature and the static credentials arrive
+	client := NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithAccessKeySecret(secret),
+		WithAuthenticator(BearerTokenHeader("X-Gateway-Authorization", "gateway-token")),
+		WithAuthenticator(BasicAuthHeader("Proxy-Authorization", "user", "pass")),
+	)
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+
+	// a bad access key does not make the token authenticator refresh a valid token
+	var fetches int64
+	gateway := NewTokenAuthenticator(TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		atomic.AddInt64(&fetches, 1)
+		return "gateway-token", time.Time{}, nil
+	}))
+	gateway.Header = "X-Gateway-Authorization"
+	client = NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithAccessKeySecret("wrong"),
+		WithAuthenticator(gateway),
+		WithAuthenticator(BasicAuthHeader("Proxy-Authorization", "user", "pass")),
+	)
+	for i := 0; i < 3; i++ {
+		ns, err = client.GetNamespace(defaultNamespace, "")
+		should.NoError(err)
+		should.Equal(http.StatusUnauthorized, ns.Status)
+	}
+	should.Equal(int64(1), atomic.LoadInt64(&fetches))
+	should.Equal(int64(3), atomic.LoadInt64(&accessKeyFailures))
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	should := require.New(t)
+
+	// the token is valid only if it's the latest one
+	var latest int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+strconv.FormatInt(atomic.LoadInt64(&latest), 10) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="apollo", error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testNamespaceBody))
+	}))
+	defer server.Close()
+
+	var fetches int64
+	expiry := time.Now().Add(time.Hour)
+	auth := NewTokenAuthenticator(TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		n := atomic.AddInt64(&fetches, 1)
+		return strconv.FormatInt(n, 10), expiry, nil
+	}))
+
+	client := NewApolloClient("myApp", WithServer(server.URL), WithAuthenticator(auth))
+
+	atomic.StoreInt64(&latest, 1)
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+
+	// cached
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+	should.Equal(int64(1), atomic.LoadInt64(&fetches))
+
+	// revoked, refreshed after 401 and sent again
+	atomic.StoreInt64(&latest, 2)
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+	should.Equal(int64(2), atomic.LoadInt64(&fetches))
+
+	// about to expire
+	expiry = time.Now().Add(time.Second)
+	atomic.StoreInt64(&latest, 4)
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusUnauthorized, ns.Status) // the token 3 is fetched after 401 but rejected, a request is sent again only once
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+	should.Equal(int64(4), atomic.LoadInt64(&fetches))
+
+	// a 401 response without Bearer challenge is not caused by the token
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+	should.False(auth.Unauthorized(resp))
+	resp.Header.Set("WWW-Authenticate", `Basic realm="apollo"`)
+	should.False(auth.Unauthorized(resp))
+	auth.IsRejected = func(*http.Response) bool { return true }
+	should.True(auth.Unauthorized(resp))
+	atomic.StoreInt64(&latest, 5)
+	ns, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal(http.StatusOK, ns.Status)
+	should.Equal(int64(5), atomic.LoadInt64(&fetches))
+
+	// failed to get token
+	client = NewApolloClient("myApp", WithServer(server.URL), WithAuthenticator(
+		NewTokenAuthenticator(TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+			return "", time.Time{}, errors.New("token server is down")
+		})),
+	))
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.ErrorContains(err, "token server is down")
+}
+
+func TestClockSkew(t *testing.T) {
+	should := require.New(t)
+
+	secret := "12848b38781e4daf9d05054580282a8e"
+	var skew, requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+
+		// the clock of apollo is ahead of local clock by skew
+		now := time.Now().Add(time.Duration(atomic.LoadInt64(&skew)))
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+
+		ms, _ := strconv.ParseInt(r.Header.Get("Timestamp"), 10, 64)
+		if d := now.Sub(time.Unix(0, ms*int64(time.Millisecond))); d > time.Minute || d < -time.Minute {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testNamespaceBody))
+	}))
+	defer server.Close()
+
+	metrics := newTestMetrics()
+	app := New("myApp", WithServer(server.URL), WithAccessKeySecret(secret), WithMetrics(metrics))
+	should.Zero(app.ClockSkew())
+
+	_, err := app.GetNamespaceFromApollo(defaultNamespace)
+	should.NoError(err)
+	should.Zero(app.ClockSkew())
+	should.Equal(int64(1), atomic.LoadInt64(&requests))
+
+	// detected by 401 and sent again with the skew applied
+	atomic.StoreInt64(&skew, int64(5*time.Minute))
+	items, err := app.GetNamespaceFromApollo(defaultNamespace)
+	should.NoError(err)
+	should.Equal("1", items.Get("a"))
+	should.InDelta((5 * time.Minute).Seconds(), app.ClockSkew().Seconds(), 2)
+	should.InDelta((5 * time.Minute).Seconds(), metrics.gauges[`lunar_clock_skew_seconds{app="myApp"}`], 2)
+	should.Equal(int64(3), atomic.LoadInt64(&requests))
+
+	// the skew is kept for later requests
+	_, err = app.GetNamespaceFromApollo(defaultNamespace)
+	should.NoError(err)
+	should.Equal(int64(4), atomic.LoadInt64(&requests))
+
+	// behind
+	atomic.StoreInt64(&skew, int64(-3*time.Minute))
+	_, err = app.GetNamespaceFromApollo(defaultNamespace)
+	should.NoError(err)
+	should.InDelta((-3 * time.Minute).Seconds(), app.ClockSkew().Seconds(), 2)
+	should.Equal(int64(6), atomic.LoadInt64(&requests))
+
+	// 401 which is not caused by skew is not sent again
+	auth := NewAccessKeyAuthenticator("myApp", secret)
+	resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+	resp.Header.Set("Date", time.Now().Add(20*time.Second).UTC().Format(http.TimeFormat))
+	should.False(auth.Unauthorized(resp))
+	resp.Header.Del("Date")
+	should.False(auth.Unauthorized(resp))
+	should.Zero(auth.ClockSkew())
+
+	// no access key
+	should.Zero(NewApolloClient("myApp").ClockSkew())
+}
@@ -5,7 +5,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 )
 
@@ -18,69 +21,308 @@ type Cache interface {
 	Drain()
 }
 
-// MemoryCache is cache stored in memory, it's the default cache for use
-type MemoryCache struct {
-	items sync.Map // key: namespace, value: items
+// CacheScoper is implemented by caches which can be shared by multiple apps and clusters,
+// Scope returns a view of the cache which only sees the namespaces of given app and cluster.
+type CacheScoper interface {
+	Scope(appID string, cluster string) Cache
 }
 
-// make sure MemoryCache implements Cache
-var _ Cache = new(MemoryCache)
+// CacheUnwrapper is implemented by caches which wrap another cache, e.g. the view of a shared cache scoped by UseCache
+type CacheUnwrapper interface {
+	Unwrap() Cache
+}
 
-// GetItems gets items from cache
-func (c *MemoryCache) GetItems(namespace string) Items {
-	if v, ok := c.items.Load(namespace); ok {
-		return v.(Items)
+// CacheViewer is implemented by caches which can get items without copying, e.g. MemoryCache.
+// The items returned by ViewItems are shared with the cache, so they must not be modified.
+type CacheViewer interface {
+	ViewItems(namespace string) Items
+}
+
+// viewItems gets items from c without copying if c implements CacheViewer, the items must not be modified
+func viewItems(c Cache, namespace string) Items {
+	if v, ok := c.(CacheViewer); ok {
+		return v.ViewItems(namespace)
+	}
+
+	return c.GetItems(namespace)
+}
+
+// NewScopedCache returns a view of c which only sees the namespaces of given app and cluster.
+// If c does not implement CacheScoper, namespaces are stored in c with a {app id}+{cluster}+ prefix,
+// so that any custom cache can be shared safely.
+func NewScopedCache(c Cache, appID string, cluster string) Cache {
+	if s, ok := c.(CacheScoper); ok {
+		return s.Scope(appID, cluster)
 	}
 
-	return Items{}
+	return newScopedCache(c, appID, cluster)
+}
+
+// scopedCache prefixes namespaces with app id and cluster
+type scopedCache struct {
+	cache  Cache
+	prefix string
+	stats  statsRecorder
+}
+
+// make sure scopedCache implements Cache, CacheStatsProvider, CacheUnwrapper and CacheViewer
+var _ Cache = new(scopedCache)
+var _ CacheStatsProvider = new(scopedCache)
+var _ CacheUnwrapper = new(scopedCache)
+var _ CacheViewer = new(scopedCache)
+
+func newScopedCache(c Cache, appID string, cluster string) *scopedCache {
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+
+	return &scopedCache{
+		cache:  c,
+		prefix: appID + "+" + cluster + "+",
+	}
+}
+
+// GetItems gets items from cache
+func (c *scopedCache) GetItems(namespace string) Items {
+	items := c.cache.GetItems(c.prefix + namespace)
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// ViewItems gets items from cache without copying if the underlying cache implements CacheViewer,
+// the items must not be modified
+func (c *scopedCache) ViewItems(namespace string) Items {
+	items := viewItems(c.cache, c.prefix+namespace)
+	c.stats.recordGet(namespace, items)
+
+	return items
 }
 
 // SetItems sets items into cache
-func (c *MemoryCache) SetItems(namespace string, items Items) error {
-	c.items.Store(namespace, items)
+func (c *scopedCache) SetItems(namespace string, items Items) error {
+	if err := c.cache.SetItems(c.prefix+namespace, items); err != nil {
+		return err
+	}
+
+	c.stats.recordSet(namespace, items)
 
 	return nil
 }
 
-// GetKeys gets all the keys (namespaces)
-func (c *MemoryCache) GetKeys() []string {
+// GetKeys gets all the keys (namespaces) in scope
+func (c *scopedCache) GetKeys() []string {
 	var keys []string
 
-	c.items.Range(func(key, value interface{}) bool {
-		keys = append(keys, key.(string))
+	for _, key := range c.cache.GetKeys() {
+		if strings.HasPrefix(key, c.prefix) {
+			keys = append(keys, strings.TrimPrefix(key, c.prefix))
+		}
+	}
 
-		return true
-	})
+	return keys
+}
+
+// Delete deletes given namespace
+func (c *scopedCache) Delete(namespace string) error {
+	if err := c.cache.Delete(c.prefix + namespace); err != nil {
+		return err
+	}
+
+	c.stats.recordDelete(namespace)
+
+	return nil
+}
+
+// Drain deletes all the namespaces in scope
+func (c *scopedCache) Drain() {
+	for _, namespace := range c.GetKeys() {
+		if err := c.Delete(namespace); err != nil {
+			log.Printf("failed to delete cache: %s", err.Error())
+		}
+	}
+}
+
+// CacheStats gets the statistics of the namespaces in scope
+func (c *scopedCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
+}
+
+// Unwrap gets the underlying cache, which has the namespaces of all the scopes
+func (c *scopedCache) Unwrap() Cache {
+	return c.cache
+}
+
+// Snapshot gets a snapshot of the namespaces in scope if the underlying cache is a MemoryCache,
+// otherwise the snapshot is empty
+func (c *scopedCache) Snapshot() *Snapshot {
+	s, ok := c.cache.(interface{ Snapshot() *Snapshot })
+	if !ok {
+		return &Snapshot{}
+	}
+
+	namespaces := make(map[string]Items)
+	for k, v := range s.Snapshot().namespaces {
+		if strings.HasPrefix(k, c.prefix) {
+			namespaces[strings.TrimPrefix(k, c.prefix)] = v
+		}
+	}
+
+	return &Snapshot{namespaces: namespaces}
+}
+
+// MemoryCache is cache stored in memory, it's the default cache for use.
+//
+// MemoryCache is copy-on-write: every write replaces the whole set of namespaces atomically,
+// so readers never block and never observe a partial update. Items passed in and returned are copies,
+// mutating them does not affect the cache.
+type MemoryCache struct {
+	lock  sync.Mutex   // serializes writers
+	state atomic.Value // value: *Snapshot
+	stats statsRecorder
+}
+
+// make sure MemoryCache implements Cache, CacheStatsProvider and CacheViewer
+var _ Cache = new(MemoryCache)
+var _ CacheStatsProvider = new(MemoryCache)
+var _ CacheViewer = new(MemoryCache)
+
+// Scope returns a view of the cache which only sees the namespaces of given app and cluster,
+// keys in the underlying cache are prefixed with {app id}+{cluster}+
+func (c *MemoryCache) Scope(appID string, cluster string) Cache {
+	return newScopedCache(c, appID, cluster)
+}
+
+// Snapshot is an immutable view of all the namespaces in a MemoryCache at a point in time
+type Snapshot struct {
+	namespaces map[string]Items
+}
+
+// Namespaces gets all the namespaces in snapshot in sorted order
+func (s *Snapshot) Namespaces() []string {
+	keys := make([]string, 0, len(s.namespaces))
+	for k := range s.namespaces {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
 	return keys
 }
 
+// GetItems gets a copy of items in given namespace
+func (s *Snapshot) GetItems(namespace string) Items {
+	return s.namespaces[namespace].Clone()
+}
+
+// ViewItems gets items in given namespace without copying, the items must not be modified
+func (s *Snapshot) ViewItems(namespace string) Items {
+	return s.namespaces[namespace]
+}
+
+// Get gets value of key in given namespace without copying
+func (s *Snapshot) Get(namespace string, key string) string {
+	return s.namespaces[namespace].Get(key)
+}
+
+// Snapshot gets a consistent view of all the namespaces, later writes are not visible in it
+func (c *MemoryCache) Snapshot() *Snapshot {
+	if s, ok := c.state.Load().(*Snapshot); ok {
+		return s
+	}
+
+	return &Snapshot{}
+}
+
+// update copies current namespaces, applies fn to the copy and publishes it
+func (c *MemoryCache) update(fn func(namespaces map[string]Items)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	current := c.Snapshot().namespaces
+	namespaces := make(map[string]Items, len(current)+1)
+	for k, v := range current {
+		namespaces[k] = v
+	}
+
+	fn(namespaces)
+
+	c.state.Store(&Snapshot{namespaces: namespaces})
+}
+
+// GetItems gets a copy of items from cache
+func (c *MemoryCache) GetItems(namespace string) Items {
+	return c.ViewItems(namespace).Clone()
+}
+
+// ViewItems gets items from cache without copying, it's O(1) no matter how many items there are.
+// The items are shared with the snapshot, so they must not be modified.
+func (c *MemoryCache) ViewItems(namespace string) Items {
+	items := c.Snapshot().ViewItems(namespace)
+	c.stats.recordGet(namespace, items)
+
+	if items == nil {
+		return Items{}
+	}
+
+	return items
+}
+
+// SetItems sets a copy of items into cache
+func (c *MemoryCache) SetItems(namespace string, items Items) error {
+	items = items.Clone()
+
+	c.update(func(namespaces map[string]Items) {
+		namespaces[namespace] = items
+	})
+
+	c.stats.recordSet(namespace, items)
+
+	return nil
+}
+
+// GetKeys gets all the keys (namespaces)
+func (c *MemoryCache) GetKeys() []string {
+	return c.Snapshot().Namespaces()
+}
+
 // Delete deletes given namespace
 func (c *MemoryCache) Delete(namespace string) error {
-	c.items.Delete(namespace)
+	c.update(func(namespaces map[string]Items) {
+		delete(namespaces, namespace)
+	})
+
+	c.stats.recordDelete(namespace)
 
 	return nil
 }
 
 // Drain deletes the whole cache
 func (c *MemoryCache) Drain() {
-	c.items.Range(func(key, value interface{}) bool {
-		c.items.Delete(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-		return true
-	})
+	c.stats.recordDelete(c.Snapshot().Namespaces()...)
+	c.state.Store(&Snapshot{})
+}
+
+// CacheStats gets the statistics of cache
+func (c *MemoryCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
 }
 
 // FileCache is cache stored in files.
 type FileCache struct {
-	lock   sync.Mutex
-	AppID  string
-	Folder string // root folder
-	Perm   os.FileMode
+	lock    sync.Mutex
+	AppID   string
+	Cluster string // empty means default cluster
+	Folder  string // root folder
+	Perm    os.FileMode
+	stats   statsRecorder
 }
 
-// make sure FileCache implements Cache
+// make sure FileCache implements Cache and CacheStatsProvider
 var _ Cache = new(FileCache)
+var _ CacheStatsProvider = new(FileCache)
 
 // NewFileCache creates a FileCache
 func NewFileCache(appID string, folder string) *FileCache {
@@ -109,15 +351,61 @@ func (c *FileCache) createAppFolder() error {
 	return nil
 }
 
+// app folder is {Folder}/{app id} for default cluster, and {Folder}/{app id}+{cluster} for other clusters
 func (c *FileCache) getAppFolder() string {
-	return filepath.Join(c.Folder, c.AppID)
+	if c.Cluster == "" || c.Cluster == defaultCluster {
+		return filepath.Join(c.Folder, c.AppID)
+	}
+
+	return filepath.Join(c.Folder, c.AppID+"+"+c.Cluster)
 }
 
-// file path is {Folder}/{app id}/{namespace}
+// Scope returns a FileCache of given app and cluster in the same folder
+func (c *FileCache) Scope(appID string, cluster string) Cache {
+	if cluster == defaultCluster {
+		cluster = ""
+	}
+
+	if c.AppID == appID && c.Cluster == cluster {
+		return c
+	}
+
+	fc := &FileCache{
+		AppID:   appID,
+		Cluster: cluster,
+		Folder:  c.Folder,
+		Perm:    c.Perm,
+	}
+
+	if err := fc.createAppFolder(); err != nil {
+		log.Printf("failed to create cache folder: %s", err.Error())
+	}
+
+	return fc
+}
+
+// file path is {app folder}/{namespace}
 func (c *FileCache) getFilePath(namespace string) string {
 	return filepath.Join(c.getAppFolder(), namespace)
 }
 
+// readFile reads the cache file of namespace. Older versions put the files of all clusters in {Folder}/{app id},
+// so the file there is read if a non-default cluster has not written its own file yet.
+func (c *FileCache) readFile(namespace string) ([]byte, error) {
+	data, err := os.ReadFile(c.getFilePath(namespace))
+	if err == nil || !os.IsNotExist(err) || c.Cluster == "" || c.Cluster == defaultCluster {
+		return data, err
+	}
+
+	legacy := filepath.Join(c.Folder, c.AppID, namespace)
+	if data, e := os.ReadFile(legacy); e == nil {
+		log.Printf("read cache from legacy folder: %s", legacy)
+		return data, nil
+	}
+
+	return nil, err
+}
+
 // GetItems gets items from cache
 func (c *FileCache) GetItems(namespace string) Items {
 	c.lock.Lock()
@@ -125,19 +413,36 @@ func (c *FileCache) GetItems(namespace string) Items {
 
 	items := make(Items)
 
-	if data, err := os.ReadFile(c.getFilePath(namespace)); err == nil {
+	if data, err := c.readFile(namespace); err == nil {
 		if IsProperties(namespace) {
-			if err := json.Unmarshal(data, &items); err != nil {
-				log.Printf("parse data error: %s", err.Error())
-			}
+			items = parseFileCacheItems(data)
 		} else {
 			items["content"] = string(data)
 		}
 	}
 
+	c.stats.recordGet(namespace, items)
+
 	return items
 }
 
+// parseFileCacheItems parses the flat items in a cache file,
+// files written by older versions hold the expanded tree, so they are flattened back
+func parseFileCacheItems(data []byte) Items {
+	items := make(Items)
+	if err := json.Unmarshal(data, &items); err == nil {
+		return items
+	}
+
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		log.Printf("parse data error: %s", err.Error())
+		return Items{}
+	}
+
+	return Flatten(tree)
+}
+
 // SetItems sets items into cache
 func (c *FileCache) SetItems(namespace string, items Items) error {
 	c.lock.Lock()
@@ -145,12 +450,23 @@ func (c *FileCache) SetItems(namespace string, items Items) error {
 
 	var content string
 	if IsProperties(namespace) {
-		content = items.String()
+		// the flat items are stored as they are, expanding them would change keys like a.0 and a[0]
+		bytes, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		content = string(bytes)
 	} else {
 		content = items.Get("content")
 	}
 
-	return os.WriteFile(c.getFilePath(namespace), []byte(content), c.Perm)
+	if err := os.WriteFile(c.getFilePath(namespace), []byte(content), c.Perm); err != nil {
+		return err
+	}
+
+	c.stats.recordSet(namespace, items)
+
+	return nil
 }
 
 // GetKeys gets all the keys (namespaces)
@@ -158,7 +474,7 @@ func (c *FileCache) GetKeys() []string {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	f, err := os.Open(filepath.Join(c.Folder, c.AppID))
+	f, err := os.Open(c.getAppFolder())
 	if err != nil {
 		return nil
 	}
@@ -171,14 +487,389 @@ func (c *FileCache) GetKeys() []string {
 
 // Delete deletes given namespace
 func (c *FileCache) Delete(namespace string) error {
-	return syscall.Unlink(c.getFilePath(namespace))
+	if err := syscall.Unlink(c.getFilePath(namespace)); err != nil {
+		return err
+	}
+
+	c.stats.recordDelete(namespace)
+
+	return nil
 }
 
 // Drain deletes the whole cache
 func (c *FileCache) Drain() {
 	for _, namespace := range c.GetKeys() {
-		if err := syscall.Unlink(c.getFilePath(namespace)); err != nil {
+		if err := c.Delete(namespace); err != nil {
+			log.Printf("failed to delete cache file: %s", err.Error())
+		}
+	}
+}
+
+// CacheStats gets the statistics of cache
+func (c *FileCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
+}
+
+// PropertiesCache is cache stored in java .properties files, it's compatible with the local cache of apollo java client,
+// so that go and java processes on the same host can share the same backup files.
+type PropertiesCache struct {
+	lock    sync.Mutex
+	AppID   string
+	Cluster string
+	Folder  string // root folder
+	Perm    os.FileMode
+	stats   statsRecorder
+}
+
+// make sure PropertiesCache implements Cache and CacheStatsProvider
+var _ Cache = new(PropertiesCache)
+var _ CacheStatsProvider = new(PropertiesCache)
+
+// NewPropertiesCache creates a PropertiesCache, the default folder is /opt/data which is the same as apollo java client
+func NewPropertiesCache(appID string, cluster string, folder string) *PropertiesCache {
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+
+	if folder == "" {
+		folder = "/opt/data"
+	}
+
+	c := &PropertiesCache{
+		AppID:   appID,
+		Cluster: cluster,
+		Folder:  folder,
+		Perm:    0644,
+	}
+
+	if err := c.createCacheFolder(); err != nil {
+		return nil
+	}
+
+	return c
+}
+
+// check and create cache folder if not existing
+func (c *PropertiesCache) createCacheFolder() error {
+	if _, err := os.Stat(c.getCacheFolder()); err != nil {
+		return os.MkdirAll(c.getCacheFolder(), os.FileMode(0755))
+	}
+	return nil
+}
+
+// Scope returns a PropertiesCache of given app and cluster in the same folder
+func (c *PropertiesCache) Scope(appID string, cluster string) Cache {
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+
+	if c.AppID == appID && c.Cluster == cluster {
+		return c
+	}
+
+	pc := &PropertiesCache{
+		AppID:   appID,
+		Cluster: cluster,
+		Folder:  c.Folder,
+		Perm:    c.Perm,
+	}
+
+	if err := pc.createCacheFolder(); err != nil {
+		log.Printf("failed to create cache folder: %s", err.Error())
+	}
+
+	return pc
+}
+
+// cache folder is {Folder}/{app id}/config-cache
+func (c *PropertiesCache) getCacheFolder() string {
+	return filepath.Join(c.Folder, c.AppID, "config-cache")
+}
+
+// file name is {app id}+{cluster}+{namespace}.properties
+func (c *PropertiesCache) getFilePrefix() string {
+	return c.AppID + "+" + c.Cluster + "+"
+}
+
+func (c *PropertiesCache) getFilePath(namespace string) string {
+	return filepath.Join(c.getCacheFolder(), c.getFilePrefix()+namespace+".properties")
+}
+
+// GetItems gets items from cache
+func (c *PropertiesCache) GetItems(namespace string) Items {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f, err := os.Open(c.getFilePath(namespace))
+	if err != nil {
+		c.stats.recordGet(namespace, nil)
+		return Items{}
+	}
+	defer f.Close()
+
+	items, err := ParseProperties(f)
+	if err != nil {
+		log.Printf("parse data error: %s", err.Error())
+		items = Items{}
+	}
+
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// SetItems sets items into cache, the file is written to a temporary file first and then renamed,
+// so that readers never see a partially written file
+func (c *PropertiesCache) SetItems(namespace string, items Items) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.getFilePath(namespace)
+
+	f, err := os.CreateTemp(c.getCacheFolder(), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := writeProperties(f, items, "Persisted by lunar"); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), c.Perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	c.stats.recordSet(namespace, items)
+
+	return nil
+}
+
+// GetKeys gets all the keys (namespaces)
+func (c *PropertiesCache) GetKeys() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f, err := os.Open(c.getCacheFolder())
+	if err != nil {
+		return nil
+	}
+
+	names, _ := f.Readdirnames(-1)
+	f.Close()
+
+	prefix := c.getFilePrefix()
+
+	var keys []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".properties") {
+			keys = append(keys, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".properties"))
+		}
+	}
+
+	return keys
+}
+
+// Delete deletes given namespace
+func (c *PropertiesCache) Delete(namespace string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := os.Remove(c.getFilePath(namespace)); err != nil {
+		return err
+	}
+
+	c.stats.recordDelete(namespace)
+
+	return nil
+}
+
+// Drain deletes the whole cache
+func (c *PropertiesCache) Drain() {
+	for _, namespace := range c.GetKeys() {
+		if err := c.Delete(namespace); err != nil {
 			log.Printf("failed to delete cache file: %s", err.Error())
 		}
 	}
 }
+
+// CacheStats gets the statistics of cache
+func (c *PropertiesCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
+}
+
+// TieredCache is a two-level cache, usually a MemoryCache in front of a persistent cache like FileCache.
+// Writes go through both tiers, reads are served by the front tier and fall back to the back tier,
+// items found in the back tier are promoted to the front tier.
+type TieredCache struct {
+	Front Cache
+	Back  Cache
+	stats statsRecorder
+	warm  sync.Once // warms up the front tier on first read
+}
+
+// make sure TieredCache implements Cache, CacheStatsProvider and CacheViewer
+var _ Cache = new(TieredCache)
+var _ CacheStatsProvider = new(TieredCache)
+var _ CacheViewer = new(TieredCache)
+
+// NewTieredCache creates a TieredCache, a MemoryCache is used if front is nil, and a nil back makes it front-only.
+// The front tier is warmed up from the back tier on first read, so a cache which is scoped by UseCache
+// only warms up the scoped tiers.
+func NewTieredCache(front Cache, back Cache) *TieredCache {
+	if front == nil {
+		front = new(MemoryCache)
+	}
+
+	return &TieredCache{
+		Front: front,
+		Back:  back,
+	}
+}
+
+// Scope returns a TieredCache composing the scoped views of both tiers, it's warmed up on first read
+func (c *TieredCache) Scope(appID string, cluster string) Cache {
+	var back Cache
+	if c.Back != nil {
+		back = NewScopedCache(c.Back, appID, cluster)
+	}
+
+	return NewTieredCache(NewScopedCache(c.Front, appID, cluster), back)
+}
+
+// warmUp warms up the front tier once
+func (c *TieredCache) warmUp() {
+	c.warm.Do(c.Warm)
+}
+
+// Warm loads all the namespaces in back tier into front tier
+func (c *TieredCache) Warm() {
+	if c.Back == nil {
+		return
+	}
+
+	for _, namespace := range c.Back.GetKeys() {
+		if items := c.Back.GetItems(namespace); len(items) > 0 {
+			if err := c.Front.SetItems(namespace, items); err != nil {
+				log.Printf("failed to warm up cache: %s", err.Error())
+			}
+		}
+	}
+}
+
+// GetItems gets items from front tier first, and then back tier
+func (c *TieredCache) GetItems(namespace string) Items {
+	c.warmUp()
+
+	items := c.Front.GetItems(namespace)
+	if len(items) == 0 && c.Back != nil {
+		items = c.promote(namespace)
+	}
+
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// ViewItems gets items from the front tier without copying if it implements CacheViewer, the items must not be modified
+func (c *TieredCache) ViewItems(namespace string) Items {
+	c.warmUp()
+
+	items := viewItems(c.Front, namespace)
+	if len(items) == 0 && c.Back != nil {
+		items = c.promote(namespace)
+	}
+
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// promote gets items from the back tier and sets them into the front tier
+func (c *TieredCache) promote(namespace string) Items {
+	items := c.Back.GetItems(namespace)
+	if len(items) > 0 {
+		if err := c.Front.SetItems(namespace, items); err != nil {
+			log.Printf("failed to promote cache: %s", err.Error())
+		}
+	}
+
+	return items
+}
+
+// SetItems writes items to the front tier first, and then to the back tier if the front tier succeeds.
+// If the back tier fails, its error is returned while the front tier keeps the items.
+func (c *TieredCache) SetItems(namespace string, items Items) error {
+	if err := c.Front.SetItems(namespace, items); err != nil {
+		return err
+	}
+
+	c.stats.recordSet(namespace, items)
+
+	if c.Back == nil {
+		return nil
+	}
+
+	return c.Back.SetItems(namespace, items)
+}
+
+// GetKeys gets all the keys (namespaces) in both tiers
+func (c *TieredCache) GetKeys() []string {
+	c.warmUp()
+
+	keys := c.Front.GetKeys()
+	if c.Back == nil {
+		return keys
+	}
+
+	set := make(map[string]bool)
+	for _, key := range keys {
+		set[key] = true
+	}
+
+	for _, key := range c.Back.GetKeys() {
+		if !set[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Delete deletes given namespace from both tiers
+func (c *TieredCache) Delete(namespace string) error {
+	err := c.Front.Delete(namespace)
+
+	if c.Back != nil {
+		if e := c.Back.Delete(namespace); e != nil && !os.IsNotExist(e) {
+			err = e
+		}
+	}
+
+	c.stats.recordDelete(namespace)
+
+	return err
+}
+
+// Drain deletes the whole cache in both tiers
+func (c *TieredCache) Drain() {
+	c.stats.recordDelete(c.GetKeys()...)
+	c.Front.Drain()
+	if c.Back != nil {
+		c.Back.Drain()
+	}
+}
+
+// CacheStats gets the statistics of cache, a read is a hit if either tier has the namespace
+func (c *TieredCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
+}
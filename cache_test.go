@@ -1,6 +1,10 @@
 package lunar
 
 import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -43,6 +47,95 @@ func (ts *CacheTestSuite) TestMemoryCache() {
 
 	cache.Drain()
 	should.Len(cache.GetKeys(), 0)
+
+	stats := cache.CacheStats()
+	should.EqualValues(2, stats.Hits)
+	should.EqualValues(1, stats.Misses)
+	should.EqualValues(2, stats.Sets)
+	should.EqualValues(2, stats.Deletes)
+	should.Len(stats.Namespaces, 0)
+}
+
+func (ts *CacheTestSuite) TestMemoryCacheSnapshot() {
+	should := require.New(ts.T())
+
+	items := Items{"a": "apple"}
+
+	cache := new(MemoryCache)
+	should.Empty(cache.Snapshot().Namespaces())
+
+	err := cache.SetItems("ns", items)
+	should.NoError(err)
+
+	// mutating the map passed in or returned does not affect the cache
+	items["a"] = "avocado"
+	got := cache.GetItems("ns")
+	should.Equal("apple", got.Get("a"))
+	got["a"] = "apricot"
+	should.Equal("apple", cache.GetItems("ns").Get("a"))
+
+	// items are viewed without copying
+	should.Equal(Items{"a": "apple"}, cache.ViewItems("ns"))
+	should.Equal(reflect.ValueOf(cache.ViewItems("ns")).Pointer(), reflect.ValueOf(cache.Snapshot().ViewItems("ns")).Pointer())
+	should.Empty(cache.ViewItems("other"))
+
+	snapshot := cache.Snapshot()
+
+	err = cache.SetItems("ns", Items{"a": "avocado"})
+	should.NoError(err)
+	err = cache.SetItems("ns2", Items{"b": "banana"})
+	should.NoError(err)
+
+	// later writes are not visible in the snapshot
+	should.Equal([]string{"ns"}, snapshot.Namespaces())
+	should.Equal("apple", snapshot.Get("ns", "a"))
+	should.Nil(snapshot.GetItems("ns2"))
+
+	snapshot = cache.Snapshot()
+	should.Equal([]string{"ns", "ns2"}, snapshot.Namespaces())
+	should.Equal("avocado", snapshot.Get("ns", "a"))
+	should.Equal(Items{"b": "banana"}, snapshot.GetItems("ns2"))
+}
+
+// run with -race to detect data races
+func (ts *CacheTestSuite) TestMemoryCacheConcurrency() {
+	cache := new(MemoryCache)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ns := fmt.Sprintf("ns%d", j%4)
+				ts.NoError(cache.SetItems(ns, Items{"a": ns, "b": fmt.Sprint(i)}))
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				items := cache.GetItems(fmt.Sprintf("ns%d", j%4))
+				items["a"] = "mutated"
+				delete(items, "b")
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				snapshot := cache.Snapshot()
+				for _, ns := range snapshot.Namespaces() {
+					ts.Equal(ns, snapshot.Get(ns, "a"))
+				}
+				if j%10 == 0 {
+					cache.Drain()
+				}
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 func (ts *CacheTestSuite) TestFileCache() {
@@ -51,6 +144,8 @@ func (ts *CacheTestSuite) TestFileCache() {
 	items := make(Items)
 	items["a"] = "apple"
 	items["b"] = "banana"
+	items["c.d"] = "cherry"
+	items["c.e[0]"] = "date"
 
 	items2 := make(Items)
 	items2["content"] = "this is plaintext"
@@ -61,6 +156,7 @@ func (ts *CacheTestSuite) TestFileCache() {
 	err = cache.SetItems("ns.txt", items2)
 	should.NoError(err)
 
+	should.Equal(items, cache.GetItems("ns"))
 	should.Equal("apple", cache.GetItems("ns").Get("a"))
 	should.Equal("this is plaintext", cache.GetItems("ns.txt").Get("content"))
 	should.ElementsMatch([]string{"ns", "ns.txt"}, cache.GetKeys())
@@ -73,3 +169,219 @@ func (ts *CacheTestSuite) TestFileCache() {
 	cache.Drain()
 	should.Len(cache.GetKeys(), 0)
 }
+
+func (ts *CacheTestSuite) TestFileCacheRoundTrip() {
+	should := require.New(ts.T())
+
+	items := Items{
+		"ports.0":    "80",
+		"servers[1]": "s1",
+		"x._value":   "v",
+		"x":          "1",
+		"k[a]":       "ka",
+	}
+
+	cache := NewFileCache("myRoundTripApp", ts.T().TempDir())
+	should.NoError(cache.SetItems("ns", items))
+	should.Equal(items, cache.GetItems("ns"))
+
+	// files written as expanded tree by older versions can still be read
+	should.NoError(os.WriteFile(cache.getFilePath("old"), []byte(`{"a":{"b":"1"},"c":"2"}`), 0o600))
+	should.Equal(Items{"a.b": "1", "c": "2"}, cache.GetItems("old"))
+}
+
+func (ts *CacheTestSuite) TestPropertiesCache() {
+	should := require.New(ts.T())
+
+	items := make(Items)
+	items["a"] = "apple"
+	items["b.c"] = "香蕉 = banana"
+
+	items2 := make(Items)
+	items2["content"] = "line 1\nline 2"
+
+	cache := NewPropertiesCache("myJavaApp", "", "/tmp")
+	err := cache.SetItems("ns", items)
+	should.NoError(err)
+	err = cache.SetItems("ns.txt", items2)
+	should.NoError(err)
+
+	data, err := os.ReadFile("/tmp/myJavaApp/config-cache/myJavaApp+default+ns.properties")
+	should.NoError(err)
+	should.Contains(string(data), "#Persisted by lunar\n")
+	should.Contains(string(data), "b.c=\\u9999\\u8549 \\= banana\n")
+
+	should.Equal(items, cache.GetItems("ns"))
+	should.Equal("line 1\nline 2", cache.GetItems("ns.txt").Get("content"))
+	should.ElementsMatch([]string{"ns", "ns.txt"}, cache.GetKeys())
+
+	err = cache.Delete("ns")
+	should.NoError(err)
+	should.Len(cache.GetItems("ns"), 0)
+	should.Len(cache.GetKeys(), 1)
+
+	cache.Drain()
+	should.Len(cache.GetKeys(), 0)
+}
+
+func (ts *CacheTestSuite) TestTieredCache() {
+	should := require.New(ts.T())
+
+	items := make(Items)
+	items["a"] = "apple"
+	items["b"] = "banana"
+
+	back := NewFileCache("myTieredApp", "/tmp")
+	err := back.SetItems("ns", items)
+	should.NoError(err)
+
+	// warm up on first read
+	cache := NewTieredCache(nil, back)
+	should.Empty(cache.Front.GetKeys())
+	should.Empty(cache.GetItems("other"))
+	should.Equal(items, cache.Front.GetItems("ns"))
+
+	// write through
+	items2 := make(Items)
+	items2["content"] = "this is plaintext"
+
+	err = cache.SetItems("ns.txt", items2)
+	should.NoError(err)
+	should.Equal("this is plaintext", cache.Front.GetItems("ns.txt").Get("content"))
+	should.Equal("this is plaintext", back.GetItems("ns.txt").Get("content"))
+
+	// read through
+	err = cache.Front.Delete("ns")
+	should.NoError(err)
+	should.Equal("apple", cache.GetItems("ns").Get("a"))
+	should.Equal("apple", cache.Front.GetItems("ns").Get("a"))
+	should.ElementsMatch([]string{"ns", "ns.txt"}, cache.GetKeys())
+
+	err = cache.Delete("ns")
+	should.NoError(err)
+	should.Len(cache.GetItems("ns"), 0)
+	should.Len(back.GetItems("ns"), 0)
+	should.Len(cache.GetKeys(), 1)
+
+	cache.Drain()
+	should.Len(cache.GetKeys(), 0)
+
+	// a nil back tier makes it front-only
+	cache = NewTieredCache(nil, nil)
+	should.NoError(cache.SetItems("ns", items))
+	should.Equal(items, cache.GetItems("ns"))
+	should.Equal([]string{"ns"}, cache.GetKeys())
+	scoped := cache.Scope("myTieredApp", "dev")
+	should.NoError(scoped.SetItems("ns", items2))
+	should.Equal(items2, scoped.GetItems("ns"))
+	should.NoError(cache.Delete("ns"))
+	should.Empty(cache.GetItems("ns"))
+	cache.Drain()
+	should.Len(cache.GetKeys(), 0)
+}
+
+func (ts *CacheTestSuite) TestScopedCache() {
+	should := require.New(ts.T())
+
+	shared := new(MemoryCache)
+
+	a := NewScopedCache(shared, "appA", "")
+	b := NewScopedCache(shared, "appB", "default")
+	c := NewScopedCache(shared, "appA", "dev")
+
+	should.NoError(a.SetItems(defaultNamespace, Items{"k": "a"}))
+	should.NoError(b.SetItems(defaultNamespace, Items{"k": "b"}))
+	should.NoError(c.SetItems(defaultNamespace, Items{"k": "c"}))
+
+	should.Equal("a", a.GetItems(defaultNamespace).Get("k"))
+	should.Equal("b", b.GetItems(defaultNamespace).Get("k"))
+	should.Equal("c", c.GetItems(defaultNamespace).Get("k"))
+	should.Equal([]string{defaultNamespace}, a.GetKeys())
+	should.ElementsMatch([]string{
+		"appA+default+application",
+		"appB+default+application",
+		"appA+dev+application",
+	}, shared.GetKeys())
+
+	a.Drain()
+	should.Len(a.GetKeys(), 0)
+	should.Len(shared.GetKeys(), 2)
+
+	// custom caches which do not implement CacheScoper are wrapped by the adapter
+	custom := struct{ Cache }{new(MemoryCache)}
+	d := NewScopedCache(custom, "appD", "default")
+	should.NoError(d.SetItems("ns", Items{"k": "d"}))
+	should.Equal("d", d.GetItems("ns").Get("k"))
+	should.Equal([]string{"appD+default+ns"}, custom.GetKeys())
+
+	should.NoError(d.Delete("ns"))
+	should.Len(custom.GetKeys(), 0)
+
+	// the underlying cache and a snapshot of the scope are still reachable
+	u, ok := b.(CacheUnwrapper)
+	should.True(ok)
+	should.Same(shared, u.Unwrap())
+	snapshot := b.(interface{ Snapshot() *Snapshot }).Snapshot()
+	should.Equal([]string{defaultNamespace}, snapshot.Namespaces())
+	should.Equal("b", snapshot.Get(defaultNamespace, "k"))
+}
+
+func (ts *CacheTestSuite) TestScopedTieredCache() {
+	should := require.New(ts.T())
+
+	folder := ts.T().TempDir()
+	back := NewFileCache("appA", folder)
+	should.NoError(back.SetItems("ns", Items{"k": "a"}))
+	should.NoError(NewScopedCache(back, "appB", "").SetItems("ns", Items{"k": "b"}))
+
+	front := new(MemoryCache)
+	shared := NewTieredCache(front, back)
+	a := NewScopedCache(shared, "appA", "")
+	b := NewScopedCache(shared, "appB", "")
+
+	// only the scoped tiers are warmed up, once
+	should.Equal("a", a.GetItems("ns").Get("k"))
+	should.Equal("b", b.GetItems("ns").Get("k"))
+	should.ElementsMatch([]string{"appA+default+ns", "appB+default+ns"}, front.GetKeys())
+}
+
+func (ts *CacheTestSuite) TestScopedFileCache() {
+	should := require.New(ts.T())
+
+	cache := NewFileCache("myScopedApp", "/tmp")
+	should.Equal(cache, NewScopedCache(cache, "myScopedApp", "default"))
+
+	dev := NewScopedCache(cache, "myScopedApp", "dev")
+	should.NoError(cache.SetItems("ns", Items{"k": "default"}))
+	should.NoError(dev.SetItems("ns", Items{"k": "dev"}))
+
+	should.Equal("default", cache.GetItems("ns").Get("k"))
+	should.Equal("dev", dev.GetItems("ns").Get("k"))
+	should.FileExists("/tmp/myScopedApp+dev/ns")
+
+	cache.Drain()
+	dev.Drain()
+	should.Len(cache.GetKeys(), 0)
+	should.Len(dev.GetKeys(), 0)
+
+	// files written by older versions for non-default clusters are in the folder of app
+	folder := ts.T().TempDir()
+	legacy := NewFileCache("myLegacyApp", folder)
+	should.NoError(legacy.SetItems("ns", Items{"k": "legacy"}))
+	upgraded := NewScopedCache(legacy, "myLegacyApp", "dev")
+	should.Equal("legacy", upgraded.GetItems("ns").Get("k"))
+	should.NoError(upgraded.SetItems("ns", Items{"k": "dev"}))
+	should.Equal("dev", upgraded.GetItems("ns").Get("k"))
+	should.Equal("legacy", legacy.GetItems("ns").Get("k"))
+	should.Empty(upgraded.GetItems("other"))
+
+	pc := NewPropertiesCache("myScopedJavaApp", "", "/tmp")
+	should.Equal(pc, NewScopedCache(pc, "myScopedJavaApp", ""))
+
+	pdev := NewScopedCache(pc, "myScopedJavaApp", "dev")
+	should.NoError(pdev.SetItems("ns", Items{"k": "dev"}))
+	should.FileExists("/tmp/myScopedJavaApp/config-cache/myScopedJavaApp+dev+ns.properties")
+	should.Len(pc.GetKeys(), 0)
+
+	pdev.Drain()
+}
@@ -1,11 +1,14 @@
 package lunar
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
 // ApolloAPI is the interface of apollo api
@@ -15,18 +18,31 @@ type ApolloAPI interface {
 	GetNotifications(ns Notifications) (Notifications, error)
 }
 
+// ApolloContextAPI is the context-aware apollo api, the context carries the parent span and cancels requests.
+// App uses it if the client implements it.
+type ApolloContextAPI interface {
+	ApolloAPI
+	GetCachedItemsContext(ctx context.Context, namespace string) (Items, error)
+	GetNamespaceContext(ctx context.Context, namespace string, releaseKey string) (*Namespace, error)
+	GetNotificationsContext(ctx context.Context, ns Notifications) (Notifications, error)
+}
+
 // ApolloClient is the implementation of apollo client.
 //
 // https://github.com/ctripcorp/apollo/wiki/%E5%85%B6%E5%AE%83%E8%AF%AD%E8%A8%80%E5%AE%A2%E6%88%B7%E7%AB%AF%E6%8E%A5%E5%85%A5%E6%8C%87%E5%8D%97
 type ApolloClient struct {
-	Options  // inherited options
-	AppID    string
-	Client   *http.Client
-	ClientIP string
+	Options       // inherited options
+	AppID         string
+	Client        *http.Client
+	ClientIP      string
+	Authenticator Authenticator // access key signing and authenticators in options, nil means no authentication
+	signer        *AccessKeyAuthenticator
 }
 
-// make sure ApolloClient implements ApolloAPI
+// make sure ApolloClient implements ApolloAPI, ApolloContextAPI and ClockSkewProvider
 var _ ApolloAPI = new(ApolloClient)
+var _ ApolloContextAPI = new(ApolloClient)
+var _ ClockSkewProvider = new(ApolloClient)
 
 // NewApolloClient creates a apollo client
 func NewApolloClient(appID string, opts ...Option) *ApolloClient {
@@ -36,51 +52,128 @@ func NewApolloClient(appID string, opts ...Option) *ApolloClient {
 		ClientIP: GetLocalIP(),
 	}
 
-	c.Client = &http.Client{
-		Timeout: c.ClientTimeout,
+	c.Client = c.newHTTPClient()
+
+	var auths []Authenticator
+	if len(c.AccessKeySecret) > 0 {
+		c.signer = NewAccessKeyAuthenticator(appID, c.AccessKeySecret)
+		c.signer.ServerPath = serverPath(c.Server)
+		auths = append(auths, c.signer)
+	}
+	if auths = append(auths, c.Authenticators...); len(auths) > 0 {
+		c.Authenticator = ChainAuthenticators(auths...)
 	}
 
 	return c
 }
 
-func (c *ApolloClient) get(pathWithQuery string, result interface{}) error {
-	url := c.Server + pathWithQuery
-	c.Logger.Printf("%s", url)
+// get sends a get request to apollo and returns the http status code,
+// result is only unmarshaled when the status code is 200
+func (c *ApolloClient) get(ctx context.Context, pathWithQuery string, result interface{}) (status int, err error) {
+	ctx, span := c.startSpan(ctx, SpanRequest, F("app", c.AppID), F("cluster", c.Cluster), F("endpoint", endpointOf(pathWithQuery)))
+	defer func() {
+		span.SetAttributes(F("status", status))
+		span.End(err)
+	}()
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return err
-	}
+	url := c.Server + pathWithQuery
+	c.log(LevelDebug, "request", F("app", c.AppID), F("cluster", c.Cluster), F("url", url))
 
-	if len(c.AccessKeySecret) > 0 {
-		headers := buildHeaders(pathWithQuery, c.AppID, c.AccessKeySecret)
-		for k, v := range headers {
-			req.Header.Add(k, v)
+	start := time.Now()
+	resp, err := c.do(ctx, url)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.unauthorized(resp) {
+		resp.Body.Close()
+		c.log(LevelWarn, "retry unauthorized request",
+			F("app", c.AppID),
+			F("cluster", c.Cluster),
+			F("url", url),
+			F("clock_skew", c.ClockSkew()),
+		)
+		if c.signer != nil {
+			c.setGauge(MetricClockSkew, c.ClockSkew().Seconds(), Labels{"app": c.AppID})
 		}
+		resp, err = c.do(ctx, url)
 	}
-
-	resp, err := c.Client.Do(req)
 	if err != nil {
-		return err
+		c.recordRequest(pathWithQuery, "error", start)
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return resp.StatusCode, err
 	}
 
 	if resp.StatusCode == http.StatusOK {
 		err = json.Unmarshal(body, result)
 	}
 
-	c.Logger.Printf("[%d] %s", resp.StatusCode, body)
+	c.recordRequest(pathWithQuery, strconv.Itoa(resp.StatusCode), start)
+	c.log(LevelDebug, "response",
+		F("app", c.AppID),
+		F("cluster", c.Cluster),
+		F("url", url),
+		F("status", resp.StatusCode),
+		F("latency", time.Since(start)),
+		F("body", body),
+	)
+
+	return resp.StatusCode, err
+}
+
+// do sends an authenticated get request
+func (c *ApolloClient) do(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Authenticator != nil {
+		if err := c.Authenticator.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	return c.Client.Do(req)
+}
+
+// unauthorized lets the authenticator handle the 401 response, it returns true if the request should be sent again
+func (c *ApolloClient) unauthorized(resp *http.Response) bool {
+	h, ok := c.Authenticator.(UnauthorizedHandler)
+
+	return ok && h.Unauthorized(resp)
+}
+
+// ClockSkew gets the clock skew of apollo server measured by access key signing, i.e. server time minus local time,
+// it's measured when apollo responds 401 and 0 if there is no access key or no skew is detected
+func (c *ApolloClient) ClockSkew() time.Duration {
+	if c.signer == nil {
+		return 0
+	}
 
-	return err
+	return c.signer.ClockSkew()
+}
+
+// recordRequest records the count and duration of request, status is the http status code or error
+func (c *ApolloClient) recordRequest(pathWithQuery, status string, start time.Time) {
+	if c.Metrics == nil {
+		return
+	}
+
+	labels := Labels{"app": c.AppID, "endpoint": endpointOf(pathWithQuery), "status": status}
+
+	c.incCounter(MetricRequests, 1, labels)
+	c.observeHistogram(MetricRequestDuration, time.Since(start).Seconds(), labels)
 }
 
 // GetCachedItems gets cached configs from apollo
 func (c *ApolloClient) GetCachedItems(namespace string) (Items, error) {
+	return c.GetCachedItemsContext(context.Background(), namespace)
+}
+
+// GetCachedItemsContext gets cached configs from apollo with context
+func (c *ApolloClient) GetCachedItemsContext(ctx context.Context, namespace string) (Items, error) {
 	url := fmt.Sprintf("/configfiles/json/%s/%s/%s?ip=%s",
 		url.QueryEscape(c.AppID),
 		url.QueryEscape(c.Cluster),
@@ -89,7 +182,7 @@ func (c *ApolloClient) GetCachedItems(namespace string) (Items, error) {
 	)
 
 	var res Items
-	err := c.get(url, &res)
+	_, err := c.get(ctx, url, &res)
 
 	return res, err
 }
@@ -101,10 +194,16 @@ type Namespace struct {
 	Name       string `json:"namespaceName"`
 	Items      Items  `json:"configurations"`
 	ReleaseKey string `json:"releaseKey"`
+	Status     int    `json:"-"` // http status code, 304 means not modified and 404 means not found
 }
 
 // GetNamespace gets realtime namespace data from apollo
 func (c *ApolloClient) GetNamespace(namespace string, releaseKey string) (*Namespace, error) {
+	return c.GetNamespaceContext(context.Background(), namespace, releaseKey)
+}
+
+// GetNamespaceContext gets realtime namespace data from apollo with context
+func (c *ApolloClient) GetNamespaceContext(ctx context.Context, namespace string, releaseKey string) (*Namespace, error) {
 	if namespace == "" {
 		namespace = defaultNamespace
 	}
@@ -118,7 +217,8 @@ func (c *ApolloClient) GetNamespace(namespace string, releaseKey string) (*Names
 	)
 
 	var res Namespace
-	err := c.get(url, &res)
+	status, err := c.get(ctx, url, &res)
+	res.Status = status
 
 	return &res, err
 }
@@ -140,6 +240,11 @@ type Notification struct {
 
 // GetNotifications gets notifications from apollo
 func (c *ApolloClient) GetNotifications(ns Notifications) (Notifications, error) {
+	return c.GetNotificationsContext(context.Background(), ns)
+}
+
+// GetNotificationsContext gets notifications from apollo with context
+func (c *ApolloClient) GetNotificationsContext(ctx context.Context, ns Notifications) (Notifications, error) {
 	if len(ns) == 0 {
 		ns = append(ns, Notification{Namespace: defaultNamespace, NotificationID: defaultNotificationID})
 	}
@@ -151,7 +256,7 @@ func (c *ApolloClient) GetNotifications(ns Notifications) (Notifications, error)
 	)
 
 	var res Notifications
-	err := c.get(url, &res)
+	_, err := c.get(ctx, url, &res)
 
 	return res, err
 }
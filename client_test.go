@@ -82,6 +82,7 @@ func (ts *ApolloClientTestSuite) TestGetNamespace() {
 	should.NoError(err)
 	should.Len(res.Items, 2)
 	should.Equal("20170430092936-dee2d58e74515ff3", res.ReleaseKey)
+	should.Equal(http.StatusOK, res.Status)
 
 	gock.New(ts.client.Server).
 		Get(url).
@@ -91,6 +92,7 @@ func (ts *ApolloClientTestSuite) TestGetNamespace() {
 
 	should.NoError(err)
 	should.Len(res.Items, 0)
+	should.Equal(http.StatusNotModified, res.Status)
 }
 
 func (ts *ApolloClientTestSuite) TestGetNotifications() {
@@ -126,3 +128,37 @@ func (ts *ApolloClientTestSuite) TestGetNotifications() {
 	should.NoError(err)
 	should.Len(res, 0)
 }
+
+func (ts *ApolloClientTestSuite) TestLogRedacted() {
+	should := require.New(ts.T())
+
+	var logs []string
+	client := NewApolloClient("RedactedApp", WithLogger(LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})))
+
+	gock.New(client.Server).
+		Get("/configs/RedactedApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"RedactedApp","namespaceName":"application","configurations":{"db.password":"s3cr3t"}}`)
+
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal("s3cr3t", ns.Items.Get("db.password"))
+
+	should.Len(logs, 2)
+	should.Contains(logs[1], `"db.password":"******"`)
+	should.NotContains(logs[1], "s3cr3t")
+
+	// redaction can be disabled
+	logs = nil
+	client.Redactor = nil
+	gock.New(client.Server).
+		Get("/configs/RedactedApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"RedactedApp","namespaceName":"application","configurations":{"db.password":"s3cr3t"}}`)
+
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Contains(logs[1], "s3cr3t")
+}
@@ -0,0 +1,187 @@
+package lunar
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	encryptedPrefix = "ENC("
+	encryptedSuffix = ")"
+)
+
+// Decryptor decrypts values like ENC(ciphertext), the ciphertext inside ENC() is passed to Decrypt
+type Decryptor interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// DecryptorFunc is a bridge between Decryptor and any decryption function
+type DecryptorFunc func(string) (string, error)
+
+// Decrypt implements Decryptor interface
+func (f DecryptorFunc) Decrypt(ciphertext string) (string, error) { return f(ciphertext) }
+
+// DecryptError is returned when values of some keys in a namespace can not be decrypted,
+// the other keys are still readable
+type DecryptError struct {
+	Namespace string
+	Errs      map[string]error // key: the key of item, value: the error of decryption
+}
+
+// Error implements error interface, values are never put into error because they may be partially decrypted
+func (e *DecryptError) Error() string {
+	keys := make([]string, 0, len(e.Errs))
+	for k := range e.Errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 1 {
+		return e.keyError(keys[0]).Error()
+	}
+
+	return fmt.Sprintf("fail to decrypt keys %s in namespace %s", strings.Join(keys, ", "), e.Namespace)
+}
+
+// keyError gets the error of given key, nil if the key is decrypted
+func (e *DecryptError) keyError(key string) error {
+	err, ok := e.Errs[key]
+	if !ok {
+		return nil
+	}
+
+	return fmt.Errorf("fail to decrypt key %s in namespace %s: %w", key, e.Namespace, err)
+}
+
+// sub gets the error of keys under given prefix, nil if all of them are decrypted
+func (e *DecryptError) sub(prefix string) error {
+	errs := make(map[string]error)
+	for k, err := range e.Errs {
+		if len(Items{k: ""}.Sub(prefix)) > 0 {
+			errs[k] = err
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &DecryptError{Namespace: e.Namespace, Errs: errs}
+}
+
+// errorOfKey gets the error which affects given key, a DecryptError only affects the keys failed to decrypt
+func errorOfKey(err error, key string) error {
+	var de *DecryptError
+	if errors.As(err, &de) {
+		return de.keyError(key)
+	}
+
+	return err
+}
+
+// AESGCMDecryptor decrypts values encrypted by AES-GCM, the ciphertext is base64 encoded nonce followed by sealed data
+type AESGCMDecryptor struct {
+	aead cipher.AEAD
+}
+
+// make sure AESGCMDecryptor implements Decryptor
+var _ Decryptor = new(AESGCMDecryptor)
+
+// NewAESGCMDecryptor creates an AES-GCM decryptor, the key must be 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256
+func NewAESGCMDecryptor(key []byte) (*AESGCMDecryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCMDecryptor{aead: aead}, nil
+}
+
+// NewAESGCMDecryptorFromEnv creates an AES-GCM decryptor with base64 encoded key in given environment variable
+func NewAESGCMDecryptorFromEnv(name string) (*AESGCMDecryptor, error) {
+	encoded := strings.TrimSpace(os.Getenv(name))
+	if encoded == "" {
+		return nil, fmt.Errorf("environment variable %s is empty", name)
+	}
+
+	return newAESGCMDecryptorFromBase64(encoded)
+}
+
+// NewAESGCMDecryptorFromFile creates an AES-GCM decryptor with base64 encoded key in given file
+func NewAESGCMDecryptorFromFile(path string) (*AESGCMDecryptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAESGCMDecryptorFromBase64(strings.TrimSpace(string(b)))
+}
+
+func newAESGCMDecryptorFromBase64(encoded string) (*AESGCMDecryptor, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("key is not base64 encoded")
+	}
+
+	return NewAESGCMDecryptor(key)
+}
+
+// Decrypt decrypts base64 encoded ciphertext
+func (d *AESGCMDecryptor) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", errors.New("ciphertext is not base64 encoded")
+	}
+
+	size := d.aead.NonceSize()
+	if len(data) < size+d.aead.Overhead() {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	plaintext, err := d.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Encrypt encrypts plaintext with a random nonce, the result is like ENC(ciphertext) which can be stored in apollo
+func (d *AESGCMDecryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, d.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	data := d.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data) + encryptedSuffix, nil
+}
+
+// decryptedValues is the decrypted values of a namespace at a release
+type decryptedValues struct {
+	releaseKey string
+	values     sync.Map // key: ciphertext, value: plaintext
+}
+
+// unwrapEncrypted gets the ciphertext of value like ENC(ciphertext)
+func unwrapEncrypted(value string) (string, bool) {
+	if !strings.HasPrefix(value, encryptedPrefix) || !strings.HasSuffix(value, encryptedSuffix) {
+		return "", false
+	}
+
+	return value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)], true
+}
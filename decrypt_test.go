@@ -0,0 +1,83 @@
+package lunar
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESGCMDecryptor(t *testing.T) {
+	should := require.New(t)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	d, err := NewAESGCMDecryptor(key)
+	should.NoError(err)
+
+	value, err := d.Encrypt("s3cr3t")
+	should.NoError(err)
+	should.NotContains(value, "s3cr3t")
+
+	ciphertext, ok := unwrapEncrypted(value)
+	should.True(ok)
+
+	plaintext, err := d.Decrypt(ciphertext)
+	should.NoError(err)
+	should.Equal("s3cr3t", plaintext)
+
+	// tampered
+	data, _ := base64.StdEncoding.DecodeString(ciphertext)
+	data[len(data)-1] ^= 1
+	_, err = d.Decrypt(base64.StdEncoding.EncodeToString(data))
+	should.Error(err)
+
+	_, err = d.Decrypt("not base64")
+	should.Error(err)
+	_, err = d.Decrypt("YWJj")
+	should.EqualError(err, "ciphertext is too short")
+
+	_, err = NewAESGCMDecryptor([]byte("short"))
+	should.Error(err)
+
+	// key from env
+	os.Setenv("LUNAR_TEST_KEY", base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv("LUNAR_TEST_KEY")
+
+	d, err = NewAESGCMDecryptorFromEnv("LUNAR_TEST_KEY")
+	should.NoError(err)
+	plaintext, err = d.Decrypt(ciphertext)
+	should.NoError(err)
+	should.Equal("s3cr3t", plaintext)
+
+	_, err = NewAESGCMDecryptorFromEnv("LUNAR_TEST_MISSING_KEY")
+	should.EqualError(err, "environment variable LUNAR_TEST_MISSING_KEY is empty")
+
+	// key from file
+	file := filepath.Join(t.TempDir(), "key")
+	should.NoError(os.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+
+	d, err = NewAESGCMDecryptorFromFile(file)
+	should.NoError(err)
+	plaintext, err = d.Decrypt(ciphertext)
+	should.NoError(err)
+	should.Equal("s3cr3t", plaintext)
+
+	should.NoError(os.WriteFile(file, []byte("!!!"), 0600))
+	_, err = NewAESGCMDecryptorFromFile(file)
+	should.EqualError(err, "key is not base64 encoded")
+}
+
+func TestUnwrapEncrypted(t *testing.T) {
+	should := require.New(t)
+
+	ciphertext, ok := unwrapEncrypted("ENC(abc)")
+	should.True(ok)
+	should.Equal("abc", ciphertext)
+
+	_, ok = unwrapEncrypted("ENC(abc")
+	should.False(ok)
+	_, ok = unwrapEncrypted("xENC(abc)")
+	should.False(ok)
+}
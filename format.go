@@ -0,0 +1,171 @@
+package lunar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsedContent is the parsed result of a structured namespace
+type parsedContent struct {
+	releaseKey string
+	content    string
+	tree       interface{}
+	items      Items
+	view       Items // items with the raw content in key content, it's shared by reads so it must not be modified
+	err        error // the error of parsing, it's cached as well so that a broken content is parsed only once
+}
+
+// isStructured checks if the content of namespace can be parsed into a tree
+func isStructured(namespace string) bool {
+	switch GetFormat(namespace) {
+	case "json", "yml", "yaml", "xml":
+		return true
+	}
+
+	return false
+}
+
+// parseContent parses the content of structured namespace into a tree
+func parseContent(namespace string, content string) (*parsedContent, error) {
+	var tree interface{}
+	var err error
+
+	switch GetFormat(namespace) {
+	case "yml", "yaml":
+		tree, err = parseYAML(content)
+	case "json":
+		tree, err = parseJSON(content)
+	case "xml":
+		tree, err = parseXML(content)
+	default:
+		return nil, fmt.Errorf("namespace %s is not structured", namespace)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsedContent{
+		content: content,
+		tree:    tree,
+		items:   Flatten(tree),
+	}, nil
+}
+
+// parseJSON parses json content into a tree, numbers are kept as json.Number
+func parseJSON(content string) (interface{}, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// parsePath parses path like $.server.ports[0] or $['server']['ports'][0] into segments,
+// a segment is either a string key or an int index
+func parsePath(path string) ([]interface{}, error) {
+	var segments []interface{}
+
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			end := i
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			segments = append(segments, path[i:end])
+			i = end
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			s := path[i+1 : i+end]
+			if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+				segments = append(segments, s[1:len(s)-1])
+			} else if index, err := strconv.Atoi(s); err == nil {
+				segments = append(segments, index)
+			} else {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", path, s)
+			}
+			i += end + 1
+		default:
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			// the leading key without $.
+			path = "." + path[i:]
+			i = 0
+		}
+	}
+
+	return segments, nil
+}
+
+// queryPath gets the node of tree at given path
+func queryPath(tree interface{}, path string) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node := tree
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case string:
+			m, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			if node, ok = m[s]; !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+		case int:
+			list, ok := node.([]interface{})
+			if s < 0 {
+				s += len(list)
+			}
+			if !ok || s < 0 || s >= len(list) {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			node = list[s]
+		}
+	}
+
+	return node, nil
+}
+
+// cloneTree deep copies maps and lists of tree
+func cloneTree(tree interface{}) interface{} {
+	switch t := tree.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = cloneTree(v)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, v := range t {
+			list[i] = cloneTree(v)
+		}
+		return list
+	}
+
+	return tree
+}
@@ -0,0 +1,75 @@
+package lunar
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContent(t *testing.T) {
+	should := require.New(t)
+
+	parsed, err := parseContent("a.json", `{"a": {"b": [1, "x", null, {"c": true}]}}`)
+	should.NoError(err)
+	should.Equal(Items{
+		"a.b[0]":   "1",
+		"a.b[1]":   "x",
+		"a.b[2]":   "",
+		"a.b[3].c": "true",
+	}, parsed.items)
+
+	parsed, err = parseContent("a.yml", "a:\n  b: 1\n")
+	should.NoError(err)
+	should.Equal(Items{"a.b": "1"}, parsed.items)
+
+	parsed, err = parseContent("a.json", "")
+	should.NoError(err)
+	should.Empty(parsed.items)
+
+	_, err = parseContent("a.json", "{")
+	should.Error(err)
+
+	_, err = parseContent("a.txt", "")
+	should.Error(err)
+}
+
+func TestQueryPath(t *testing.T) {
+	should := require.New(t)
+
+	tree, err := parseJSON(`{"server": {"ports": [80, 443], "a.b": "dot"}}`)
+	should.NoError(err)
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"$", tree},
+		{"$.server.ports[0]", json.Number("80")},
+		{"server.ports[1]", json.Number("443")},
+		{"$.server.ports[-1]", json.Number("443")},
+		{"$['server'][\"a.b\"]", "dot"},
+		{"$.server.ports", []interface{}{json.Number("80"), json.Number("443")}},
+	}
+
+	for _, test := range tests {
+		v, err := queryPath(tree, test.path)
+		should.NoError(err, test.path)
+		should.Equal(test.want, v, test.path)
+	}
+
+	for _, path := range []string{"$.foo", "$.server.ports[2]", "$.server[0]", "$.server.ports.a", "$..a", "$[x]", "$[0"} {
+		_, err := queryPath(tree, path)
+		should.Error(err, path)
+	}
+}
+
+func TestCloneTree(t *testing.T) {
+	should := require.New(t)
+
+	tree := map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "c"}}}
+	clone := cloneTree(tree).(map[string]interface{})
+	clone["a"].([]interface{})[0].(map[string]interface{})["b"] = "d"
+
+	should.Equal("c", tree["a"].([]interface{})[0].(map[string]interface{})["b"])
+}
@@ -0,0 +1,215 @@
+package lunar
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	placeholderPrefix = "${"
+	placeholderSuffix = '}'
+	placeholderSep    = ':'
+	envPlaceholder    = "env" // ${env:HOME} refers to environment variable
+)
+
+// interpolator resolves placeholders like ${key}, ${key:default}, ${ns:key} and ${env:HOME} in values
+type interpolator struct {
+	lookup      func(namespace, key string) (string, bool, error) // looks up the raw value of key in namespace
+	isNamespace func(name string) (bool, error)                   // checks if name can be referenced as namespace, nil means never
+	stack       []string                                          // references being resolved, for cycle detection
+}
+
+// Interpolate returns new items with placeholders in values resolved, keys are looked up in the items:
+//
+//	${key} is the value of key, which is resolved as well
+//	${key:default} is the value of key or default if key does not exist
+//	${env:HOME} is the environment variable HOME, ${env:HOME:default} has a default as well
+//
+// It returns an error if a placeholder can not be resolved or the references form a cycle.
+func (items Items) Interpolate() (Items, error) {
+	in := &interpolator{
+		lookup: func(_, key string) (string, bool, error) {
+			v, ok := items[key]
+			return v, ok, nil
+		},
+	}
+
+	return in.resolveItems("", items)
+}
+
+// resolveItems resolves values of all the items in namespace
+func (in *interpolator) resolveItems(namespace string, items Items) (Items, error) {
+	resolved := make(Items, len(items))
+
+	for _, k := range items.Keys() {
+		v, _, err := in.lookupValue(namespace, k)
+		if err != nil {
+			return nil, err
+		}
+		resolved[k] = v
+	}
+
+	return resolved, nil
+}
+
+// lookupValue looks up the value of key in namespace and resolves it
+func (in *interpolator) lookupValue(namespace, key string) (string, bool, error) {
+	ref := key
+	if namespace != "" {
+		ref = namespace + string(placeholderSep) + key
+	}
+
+	for _, r := range in.stack {
+		if r == ref {
+			return "", false, fmt.Errorf("placeholder cycle: %s -> %s", strings.Join(in.stack, " -> "), ref)
+		}
+	}
+
+	raw, ok, err := in.lookup(namespace, key)
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	in.stack = append(in.stack, ref)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+	}()
+
+	v, err := in.resolve(namespace, raw)
+	if err != nil {
+		return "", false, err
+	}
+
+	return v, true, nil
+}
+
+// resolve resolves all the placeholders in value, an unterminated placeholder is kept as it is
+func (in *interpolator) resolve(namespace, value string) (string, error) {
+	if !strings.Contains(value, placeholderPrefix) {
+		return value, nil
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, placeholderPrefix)
+		if start < 0 {
+			break
+		}
+
+		end := closePlaceholder(value, start+len(placeholderPrefix))
+		if end < 0 {
+			break
+		}
+
+		v, err := in.resolvePlaceholder(namespace, value[start+len(placeholderPrefix):end])
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(value[:start])
+		sb.WriteString(v)
+		value = value[end+1:]
+	}
+	sb.WriteString(value)
+
+	return sb.String(), nil
+}
+
+// resolvePlaceholder resolves the expression inside ${}, the name may contain placeholders
+// and the default is resolved only if it's used
+func (in *interpolator) resolvePlaceholder(namespace, expr string) (string, error) {
+	name, rest, hasRest := splitPlaceholder(expr)
+
+	name, err := in.resolve(namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	if name == envPlaceholder && hasRest {
+		key, def, hasDef := splitPlaceholder(rest)
+		if key, err = in.resolve(namespace, key); err != nil {
+			return "", err
+		}
+		if v, ok := os.LookupEnv(key); ok {
+			return v, nil
+		}
+		if hasDef {
+			return in.resolve(namespace, def)
+		}
+		return "", fmt.Errorf("unresolved placeholder ${%s}", expr)
+	}
+
+	// key in the same namespace
+	if v, ok, err := in.lookupValue(namespace, name); err != nil || ok {
+		return v, err
+	}
+
+	// key in another namespace
+	isNamespace := false
+	if hasRest && in.isNamespace != nil {
+		if isNamespace, err = in.isNamespace(name); err != nil {
+			return "", err
+		}
+	}
+	if isNamespace {
+		key, def, hasDef := splitPlaceholder(rest)
+		if key, err = in.resolve(namespace, key); err != nil {
+			return "", err
+		}
+		if v, ok, err := in.lookupValue(name, key); err != nil || ok {
+			return v, err
+		}
+		if hasDef {
+			return in.resolve(namespace, def)
+		}
+		return "", fmt.Errorf("unresolved placeholder ${%s}", expr)
+	}
+
+	if hasRest {
+		return in.resolve(namespace, rest)
+	}
+
+	return "", fmt.Errorf("unresolved placeholder ${%s}", expr)
+}
+
+// closePlaceholder finds the index of } which closes the placeholder starting before i,
+// nested placeholders are skipped. It returns -1 if not found.
+func closePlaceholder(s string, i int) int {
+	depth := 1
+
+	for ; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], placeholderPrefix) {
+			depth++
+			i++
+			continue
+		}
+		if s[i] == placeholderSuffix {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitPlaceholder splits expr at the first : which is not in a nested placeholder
+func splitPlaceholder(expr string) (string, string, bool) {
+	depth := 0
+
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case strings.HasPrefix(expr[i:], placeholderPrefix):
+			depth++
+			i++
+		case expr[i] == placeholderSuffix:
+			depth--
+		case expr[i] == placeholderSep && depth == 0:
+			return expr[:i], expr[i+1:], true
+		}
+	}
+
+	return expr, "", false
+}
@@ -0,0 +1,51 @@
+package lunar
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	should := require.New(t)
+
+	os.Setenv("LUNAR_TEST_HOME", "/home/lunar")
+	defer os.Unsetenv("LUNAR_TEST_HOME")
+
+	items, err := Items{
+		"host":     "localhost",
+		"port":     "3306",
+		"url":      "mysql://${host}:${port}/${db:test}",
+		"home":     "${env:LUNAR_TEST_HOME}/conf",
+		"shell":    "${env:LUNAR_TEST_SHELL:/bin/sh}",
+		"nested":   "${missing:${host}}",
+		"name":     "${${key}}",
+		"key":      "host",
+		"plain":    "a ${ b",
+		"timeout":  "${timeout.ms:30}s",
+		"fallback": "${missing:http://${host}}",
+	}.Interpolate()
+
+	should.NoError(err)
+	should.Equal("mysql://localhost:3306/test", items["url"])
+	should.Equal("/home/lunar/conf", items["home"])
+	should.Equal("/bin/sh", items["shell"])
+	should.Equal("localhost", items["nested"])
+	should.Equal("localhost", items["name"])
+	should.Equal("a ${ b", items["plain"])
+	should.Equal("30s", items["timeout"])
+	should.Equal("http://localhost", items["fallback"])
+
+	_, err = Items{"a": "${b}"}.Interpolate()
+	should.EqualError(err, "unresolved placeholder ${b}")
+
+	_, err = Items{"a": "${env:LUNAR_TEST_MISSING}"}.Interpolate()
+	should.EqualError(err, "unresolved placeholder ${env:LUNAR_TEST_MISSING}")
+
+	_, err = Items{"a": "${b}", "b": "x${c}", "c": "${a}"}.Interpolate()
+	should.EqualError(err, "placeholder cycle: a -> b -> c -> a")
+
+	_, err = Items{"a": "${a:1}"}.Interpolate()
+	should.EqualError(err, "placeholder cycle: a -> a")
+}
@@ -2,6 +2,10 @@ package lunar
 
 import (
 	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -17,40 +21,284 @@ func (items Items) Get(key string) string {
 	return ""
 }
 
-// String converts Items to json string
+// Clone returns a copy of items, nil items is cloned to nil
+func (items Items) Clone() Items {
+	if items == nil {
+		return nil
+	}
+
+	clone := make(Items, len(items))
+	for k, v := range items {
+		clone[k] = v
+	}
+
+	return clone
+}
+
+// equal checks if items have the same keys and values
+func (items Items) equal(other Items) bool {
+	if len(items) != len(other) {
+		return false
+	}
+
+	for k, v := range items {
+		if ov, ok := other[k]; !ok || ov != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Keys returns the keys in ascending order
+func (items Items) Keys() []string {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Filter returns new items which fn returns true for
+func (items Items) Filter(fn func(key, value string) bool) Items {
+	filtered := make(Items)
+	for k, v := range items {
+		if fn(k, v) {
+			filtered[k] = v
+		}
+	}
+
+	return filtered
+}
+
+// Sub returns new items under given prefix with the prefix stripped, e.g. db.host becomes host for prefix db.
+// Keys like servers[0].host become 0.host for prefix servers, and the key equal to the prefix is dropped.
+func (items Items) Sub(prefix string) Items {
+	if prefix == "" {
+		return items.Filter(func(string, string) bool { return true })
+	}
+
+	sub := make(Items)
+	for k, v := range items {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		switch rest := k[len(prefix):]; {
+		case strings.HasPrefix(rest, "."):
+			sub[rest[1:]] = v
+		case strings.HasPrefix(rest, "["):
+			sub[unbracketIndex(rest)] = v
+		}
+	}
+
+	return sub
+}
+
+// unbracketIndex removes the brackets of the leading index of key, e.g. [0].host becomes 0.host
+// and [0][1] becomes 0[1], so that the key of a sub list can be read by Get
+func unbracketIndex(key string) string {
+	end := strings.IndexByte(key, ']')
+	if !strings.HasPrefix(key, "[") || end < 0 {
+		return key
+	}
+
+	return key[1:end] + key[end+1:]
+}
+
+// Match returns new items whose keys match given glob pattern, e.g. db.* or *password*.
+// The syntax is the same as path.Match, a malformed pattern matches nothing.
+func (items Items) Match(pattern string) Items {
+	return items.Filter(func(key, _ string) bool {
+		matched, err := path.Match(pattern, key)
+		return err == nil && matched
+	})
+}
+
+// String converts Items to json string, values of sensitive keys are masked by DefaultRedactor
+// so that it's safe to be logged
 func (items Items) String() string {
+	return DefaultRedactor.RedactItems(items).json()
+}
+
+// json converts Items to json string without redaction
+func (items Items) json() string {
 	bytes, _ := json.Marshal(items.Expand())
 	return string(bytes)
 }
 
-// Expand expands dot-key items to nested map
+// ConflictPolicy decides how to expand a key which is both a leaf and a parent, e.g. a=1 and a.b=2
+type ConflictPolicy int
+
+const (
+	// ConflictValueKey keeps the leaf value under the key "_value": {"a": {"_value": "1", "b": "2"}}
+	ConflictValueKey ConflictPolicy = iota
+	// ConflictLeafWins keeps the leaf value and drops the children: {"a": "1"}
+	ConflictLeafWins
+	// ConflictError returns an error
+	ConflictError
+)
+
+// ValueKey is the key of leaf value when a key is both a leaf and a parent
+const ValueKey = "_value"
+
+// Expand expands dot-key items to nested map, keys like servers[0].host or servers.0.host are expanded to lists
+// if the indexes are 0, 1, 2 and so on. Conflicting keys like a=1 and a.b=2 are expanded by ConflictValueKey policy.
 func (items Items) Expand() interface{} {
-	var root Node
-	for k, v := range items {
-		ks := strings.Split(k, ".")
+	tree, _ := items.ExpandWithPolicy(ConflictValueKey)
 
-		parent := root.GetChild(ks[0])
-		if parent == nil {
-			parent = &Node{
-				Name:  ks[0],
-				Value: v,
-			}
-			root.AddChildren(parent)
-		}
+	return tree
+}
+
+// ExpandWithPolicy expands dot-key items to nested map with given conflict policy
+func (items Items) ExpandWithPolicy(policy ConflictPolicy) (interface{}, error) {
+	if len(items) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	return expandNode(items.Tree(), "", policy)
+}
 
-		node := parent
-		for i := 1; i < len(ks); i++ {
-			child := node.GetChild(ks[i])
+// Tree converts dot-key items to a tree, keys like servers[0].host are split into servers, 0 and host.
+// Children are added in the order of sorted keys, the root node has no name.
+func (items Items) Tree() *Node {
+	root := new(Node)
+	for _, k := range items.Keys() {
+		node := root
+		for _, name := range splitItemKey(k) {
+			child := node.child(name)
 			if child == nil {
-				child = &Node{
-					Name:  ks[i],
-					Value: v,
-				}
+				child = &Node{Name: name}
 				node.AddChildren(child)
 			}
 			node = child
 		}
+		node.Value = items[k]
+		node.hasValue = true
+	}
+
+	return root
+}
+
+// splitItemKey splits key like servers[0].host into servers, 0 and host
+func splitItemKey(key string) []string {
+	var names []string
+
+	for _, part := range strings.Split(key, ".") {
+		indexed := false
+		for {
+			start := strings.IndexByte(part, '[')
+			end := strings.IndexByte(part, ']')
+			if start < 0 || end < start {
+				break
+			}
+			if start > 0 {
+				names = append(names, part[:start])
+			}
+			names = append(names, part[start+1:end])
+			part = part[end+1:]
+			indexed = true
+		}
+		if part != "" || !indexed {
+			names = append(names, part)
+		}
+	}
+
+	return names
+}
+
+func expandNode(node *Node, path string, policy ConflictPolicy) (interface{}, error) {
+	if node.IsLeaf() {
+		return node.Value, nil
+	}
+
+	conflict := node.hasValue && path != ""
+	if conflict {
+		switch policy {
+		case ConflictLeafWins:
+			return node.Value, nil
+		case ConflictError:
+			return nil, fmt.Errorf("key %s is both a value and a parent", path)
+		}
+	}
+
+	children := make(map[string]interface{}, len(node.Children))
+	for _, child := range node.Children {
+		v, err := expandNode(child, joinPath(path, child.Name), policy)
+		if err != nil {
+			return nil, err
+		}
+		children[child.Name] = v
+	}
+
+	if conflict {
+		children[ValueKey] = node.Value
+		return children, nil
+	}
+
+	if list, ok := toList(children); ok {
+		return list, nil
+	}
+
+	return children, nil
+}
+
+// toList converts map to list if the keys are 0, 1, 2 and so on
+func toList(m map[string]interface{}) ([]interface{}, bool) {
+	list := make([]interface{}, len(m))
+
+	for k, v := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return nil, false
+		}
+		list[i] = v
 	}
 
-	return root.ToMap()
+	return list, true
+}
+
+// Flatten converts a tree of maps and lists to dot-key items, it's the inverse of Items.Expand.
+// List elements are keyed by index like servers[0].host, and values under the key "_value"
+// are keyed by their parent keys.
+func Flatten(tree interface{}) Items {
+	items := make(Items)
+	flattenInto(items, "", tree)
+
+	return items
+}
+
+func flattenInto(items Items, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 && prefix != "" {
+			items[prefix] = ""
+		}
+		for k, child := range t {
+			if k == ValueKey && prefix != "" {
+				flattenInto(items, prefix, child)
+				continue
+			}
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flattenInto(items, k, child)
+		}
+	case []interface{}:
+		if len(t) == 0 && prefix != "" {
+			items[prefix] = ""
+		}
+		for i, child := range t {
+			flattenInto(items, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	case nil:
+		if prefix != "" {
+			items[prefix] = ""
+		}
+	case string:
+		items[prefix] = t
+	default:
+		items[prefix] = fmt.Sprint(t)
+	}
 }
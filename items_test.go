@@ -1,6 +1,7 @@
 package lunar
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -35,3 +36,188 @@ func (ts *ItemsTestSuite) TestString() {
 
 	should.Equal("{\"a\":\"apple\",\"b\":\"banana\"}", items.String())
 }
+
+func (ts *ItemsTestSuite) TestClone() {
+	should := require.New(ts.T())
+
+	var items Items
+	should.Nil(items.Clone())
+
+	items = Items{"a": "apple"}
+	clone := items.Clone()
+	clone["a"] = "avocado"
+	should.Equal("apple", items.Get("a"))
+}
+
+func (ts *ItemsTestSuite) TestKeys() {
+	should := require.New(ts.T())
+
+	should.Empty(Items{}.Keys())
+	should.Equal([]string{"a", "b.a", "b.b"}, Items{"b.b": "2", "a": "0", "b.a": "1"}.Keys())
+}
+
+func (ts *ItemsTestSuite) TestFilter() {
+	should := require.New(ts.T())
+
+	items := Items{"a": "1", "b": "2", "c": "3"}
+	filtered := items.Filter(func(k, v string) bool {
+		return k != "b" && v != "3"
+	})
+
+	should.Equal(Items{"a": "1"}, filtered)
+	should.Len(items, 3)
+}
+
+func (ts *ItemsTestSuite) TestSub() {
+	should := require.New(ts.T())
+
+	items := Items{
+		"db":              "mysql",
+		"db.host":         "localhost",
+		"db.pool.max":     "10",
+		"db[0]":           "r0",
+		"dbx.host":        "remote",
+		"servers[0].host": "s1",
+	}
+
+	should.Equal(Items{"host": "localhost", "pool.max": "10", "0": "r0"}, items.Sub("db"))
+	should.Equal(Items{"max": "10"}, items.Sub("db.pool"))
+	// the leading index of a sub list can be read by Get
+	servers := items.Sub("servers")
+	should.Equal(Items{"0.host": "s1"}, servers)
+	should.Equal("s1", servers.Get("0.host"))
+	should.Equal(Items{"0[1]": "m"}, Items{"matrix[0][1]": "m"}.Sub("matrix"))
+	should.Equal(Items{"host": "s1"}, items.Sub("servers[0]"))
+	should.Empty(items.Sub("cache"))
+
+	all := items.Sub("")
+	should.Equal(items, all)
+	all["db"] = "pg"
+	should.Equal("mysql", items.Get("db"))
+}
+
+func (ts *ItemsTestSuite) TestMatch() {
+	should := require.New(ts.T())
+
+	items := Items{
+		"db.password":      "secret",
+		"db.host":          "localhost",
+		"redis.password":   "secret",
+		"admin_password_1": "secret",
+	}
+
+	should.Equal(Items{"db.password": "secret", "db.host": "localhost"}, items.Match("db.*"))
+	should.Len(items.Match("*password*"), 3)
+	should.Equal(Items{"db.host": "localhost"}, items.Match("db.h?st"))
+	should.Empty(items.Match("[db"))
+}
+
+func (ts *ItemsTestSuite) TestStringOfEmptyItems() {
+	should := require.New(ts.T())
+
+	should.Equal("{}", Items{}.String())
+}
+
+func (ts *ItemsTestSuite) TestFlatten() {
+	should := require.New(ts.T())
+
+	tree := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": "1",
+			"c": []interface{}{"x", map[string]interface{}{"d": 2}},
+		},
+		"e": nil,
+		"f": []interface{}{},
+		"g": map[string]interface{}{},
+	}
+
+	should.Equal(Items{
+		"a.b":      "1",
+		"a.c[0]":   "x",
+		"a.c[1].d": "2",
+		"e":        "",
+		"f":        "",
+		"g":        "",
+	}, Flatten(tree))
+}
+
+func (ts *ItemsTestSuite) TestExpand() {
+	should := require.New(ts.T())
+
+	items := Items{
+		"servers[0].host": "s1",
+		"servers[1].host": "s2",
+		"ports.0":         "80",
+		"ports.1":         "443",
+		"matrix[0][1]":    "x",
+		"matrix[0][0]":    "y",
+		"codes.404":       "not found",
+		"a":               "1",
+		"a.b":             "2",
+	}
+
+	should.Equal(map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "s1"},
+			map[string]interface{}{"host": "s2"},
+		},
+		"ports":  []interface{}{"80", "443"},
+		"matrix": []interface{}{[]interface{}{"y", "x"}},
+		"codes":  map[string]interface{}{"404": "not found"},
+		"a":      map[string]interface{}{"_value": "1", "b": "2"},
+	}, items.Expand())
+
+	tree, err := items.ExpandWithPolicy(ConflictLeafWins)
+	should.NoError(err)
+	should.Equal("1", tree.(map[string]interface{})["a"])
+
+	_, err = items.ExpandWithPolicy(ConflictError)
+	should.EqualError(err, "key a is both a value and a parent")
+}
+
+func (ts *ItemsTestSuite) TestFlattenRoundTrip() {
+	should := require.New(ts.T())
+
+	items := Items{
+		"servers[0].host": "s1",
+		"servers[1].host": "s2",
+		"matrix[0][0]":    "y",
+		"a":               "1",
+		"a.b":             "2",
+		"a.c.d":           "",
+		"e":               "",
+	}
+
+	should.Equal(items, Flatten(items.Expand()))
+
+	// dot indexes are flattened to brackets
+	should.Equal(Items{"ports[0]": "80"}, Flatten(Items{"ports.0": "80"}.Expand()))
+}
+
+// newBenchmarkItems creates items with n keys like group1.key11, each group has n/10 keys
+func newBenchmarkItems(n int) Items {
+	items := make(Items, n)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("group%d.key%d", i%10, i)] = fmt.Sprint(i)
+	}
+
+	return items
+}
+
+func BenchmarkExpand10k(b *testing.B) {
+	items := newBenchmarkItems(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		items.Expand()
+	}
+}
+
+func BenchmarkString10k(b *testing.B) {
+	items := newBenchmarkItems(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = items.String()
+	}
+}
@@ -1,6 +1,10 @@
 package lunar
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"strings"
+)
 
 // Logger is logger interface
 type Logger interface {
@@ -13,8 +17,138 @@ type LoggerFunc func(string, ...interface{})
 // Printf implements Logger interface
 func (f LoggerFunc) Printf(msg string, args ...interface{}) { f(msg, args...) }
 
-// defaultLogger writes nothing
-var defaultLogger = LoggerFunc(func(string, ...interface{}) {})
+// nopLogger writes nothing
+type nopLogger struct{}
+
+// Printf implements Logger interface
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// defaultLogger writes nothing, logs are not even formatted for it
+var defaultLogger Logger = nopLogger{}
 
 // Printf is a logger which wraps log.Printf
 var Printf = LoggerFunc(log.Printf)
+
+// Level is the severity of log
+type Level int
+
+const (
+	// LevelDebug is for requests and responses
+	LevelDebug Level = iota
+	// LevelInfo is for lifecycle events like stop watching
+	LevelInfo
+	// LevelWarn is for failures which can be recovered
+	LevelWarn
+	// LevelError is for failures of fetching data
+	LevelError
+)
+
+// String gets the name of level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Field is a key/value pair attached to log, keys used by lunar are app, cluster, namespace, url, status, latency and error
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F creates a field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// StructuredLogger is a leveled logger with fields, a Logger which also implements it gets logs by Log instead of Printf
+type StructuredLogger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// logEnabled checks if a log of level will be written, so that logs which are dropped are not redacted nor formatted
+func (o *Options) logEnabled(level Level) bool {
+	return level >= o.LogLevel && o.Logger != nil && o.Logger != defaultLogger
+}
+
+// log writes a log if the level is enabled, sensitive values are masked by the redactor
+func (o *Options) log(level Level, msg string, fields ...Field) {
+	if !o.logEnabled(level) {
+		return
+	}
+
+	msg, fields = o.Redactor.RedactText(msg), o.redactFields(fields)
+
+	if l, ok := o.Logger.(StructuredLogger); ok {
+		l.Log(level, msg, fields...)
+		return
+	}
+
+	o.Logger.Printf("%s", formatLog(level, msg, fields))
+}
+
+// redactFields masks fields with sensitive keys and sensitive values in string fields
+func (o *Options) redactFields(fields []Field) []Field {
+	if o.Redactor == nil {
+		return fields
+	}
+
+	redacted := make([]Field, len(fields))
+	for i, f := range fields {
+		switch v := f.Value.(type) {
+		case string:
+			f.Value = o.Redactor.RedactText(v)
+		case []byte:
+			f.Value = o.Redactor.RedactText(string(v))
+		case error:
+			f.Value = o.Redactor.RedactText(v.Error())
+		}
+		if o.Redactor.Match(f.Key) {
+			f.Value = o.Redactor.mask()
+		}
+		redacted[i] = f
+	}
+
+	return redacted
+}
+
+// formatLog formats a log line like [ERROR] msg key=value
+func formatLog(level Level, msg string, fields []Field) string {
+	var sb strings.Builder
+
+	sb.WriteString("[" + level.String() + "] " + msg)
+	for _, f := range fields {
+		var v string
+		switch t := f.Value.(type) {
+		case string:
+			v = quoteLogValue(t)
+		case []byte:
+			v = quoteLogValue(string(t))
+		case error:
+			v = quoteLogValue(t.Error())
+		default:
+			v = fmt.Sprint(t)
+		}
+		sb.WriteString(" " + f.Key + "=" + v)
+	}
+
+	return sb.String()
+}
+
+// quoteLogValue quotes empty value and value with spaces
+func quoteLogValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\r\n") {
+		return fmt.Sprintf("%q", v)
+	}
+
+	return v
+}
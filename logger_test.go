@@ -0,0 +1,77 @@
+package lunar
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testStructuredLogger struct {
+	levels []Level
+	msgs   []string
+	fields [][]Field
+}
+
+func (l *testStructuredLogger) Printf(format string, args ...interface{}) {
+	panic("Printf should not be called")
+}
+
+func (l *testStructuredLogger) Log(level Level, msg string, fields ...Field) {
+	l.levels = append(l.levels, level)
+	l.msgs = append(l.msgs, msg)
+	l.fields = append(l.fields, fields)
+}
+
+func TestLog(t *testing.T) {
+	should := require.New(t)
+
+	var lines []string
+	o := NewOptions(WithLogger(LoggerFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})))
+
+	o.log(LevelDebug, "response", F("status", 200), F("latency", 15*time.Millisecond), F("body", []byte(`{"token":"abc"}`)))
+	o.log(LevelError, "fail to get data", F("namespace", "application"), F("error", errors.New("i/o timeout")), F("empty", ""))
+	o.log(LevelInfo, "db.password=abc", F("db.secret", 1))
+
+	should.Equal([]string{
+		`[DEBUG] response status=200 latency=15ms body={"token":"******"}`,
+		`[ERROR] fail to get data namespace=application error="i/o timeout" empty=""`,
+		`[INFO] db.password=****** db.secret=******`,
+	}, lines)
+
+	// levels below LogLevel are dropped
+	lines = nil
+	o.LogLevel = LevelWarn
+	o.log(LevelInfo, "stop watching")
+	o.log(LevelWarn, "fail to delete cache")
+	should.Equal([]string{"[WARN] fail to delete cache"}, lines)
+
+	// structured logger gets fields
+	l := new(testStructuredLogger)
+	o = NewOptions(WithLogger(l), WithLogLevel(LevelInfo))
+	o.log(LevelDebug, "request")
+	o.log(LevelError, "fail to fetch notifications", F("app", "SampleApp"), F("error", errors.New("password: abc")))
+
+	should.Equal([]Level{LevelError}, l.levels)
+	should.Equal([]string{"fail to fetch notifications"}, l.msgs)
+	should.Equal([]Field{F("app", "SampleApp"), F("error", "password: ******")}, l.fields[0])
+
+	// nothing is redacted or formatted for the default logger
+	o = NewOptions()
+	should.False(o.logEnabled(LevelError))
+	o = NewOptions(WithLogger(Printf), WithLogLevel(LevelInfo))
+	should.True(o.logEnabled(LevelInfo))
+	should.False(o.logEnabled(LevelDebug))
+}
+
+func TestLevelString(t *testing.T) {
+	should := require.New(t)
+
+	should.Equal("DEBUG", LevelDebug.String())
+	should.Equal("WARN", LevelWarn.String())
+	should.Equal("LEVEL(9)", Level(9).String())
+}
@@ -0,0 +1,188 @@
+package lunar
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// names of metrics, durations are in seconds
+const (
+	MetricRequests                 = "lunar_requests_total"                 // labels: app, endpoint, status
+	MetricRequestDuration          = "lunar_request_duration_seconds"       // labels: app, endpoint, status
+	MetricLongPolls                = "lunar_long_polls_total"               // labels: app
+	MetricLongPollFailures         = "lunar_long_poll_failures_total"       // labels: app
+	MetricLongPollConsecutiveFails = "lunar_long_poll_consecutive_failures" // labels: app
+	MetricNotifications            = "lunar_notifications_total"            // labels: app, namespace
+	MetricCacheHits                = "lunar_cache_hits_total"               // labels: app, namespace
+	MetricCacheMisses              = "lunar_cache_misses_total"             // labels: app, namespace
+	MetricCacheSets                = "lunar_cache_sets_total"               // labels: app, namespace
+	MetricCacheDeletes             = "lunar_cache_deletes_total"            // labels: app, namespace
+	MetricCacheSize                = "lunar_cache_size"                     // labels: app, namespace
+	MetricClockSkew                = "lunar_clock_skew_seconds"             // server time minus local time, labels: app
+)
+
+// Labels are the dimensions of a metric
+type Labels map[string]string
+
+// Metrics collects counters, histograms and gauges, it must be safe for concurrent use
+type Metrics interface {
+	IncCounter(name string, delta float64, labels Labels)
+	ObserveHistogram(name string, value float64, labels Labels)
+	SetGauge(name string, value float64, labels Labels)
+}
+
+func (o *Options) incCounter(name string, delta float64, labels Labels) {
+	if o.Metrics != nil {
+		o.Metrics.IncCounter(name, delta, labels)
+	}
+}
+
+func (o *Options) observeHistogram(name string, value float64, labels Labels) {
+	if o.Metrics != nil {
+		o.Metrics.ObserveHistogram(name, value, labels)
+	}
+}
+
+func (o *Options) setGauge(name string, value float64, labels Labels) {
+	if o.Metrics != nil {
+		o.Metrics.SetGauge(name, value, labels)
+	}
+}
+
+// endpointOf gets the endpoint of apollo api like configs, configfiles and notifications
+func endpointOf(pathWithQuery string) string {
+	endpoint := strings.TrimPrefix(pathWithQuery, "/")
+	if i := strings.IndexAny(endpoint, "/?"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+
+	return endpoint
+}
+
+// ExpvarMetrics exports metrics by expvar, they are published as a map keyed by metric name with labels,
+// e.g. lunar_requests_total{app="myApp",endpoint="configs",status="200"}.
+// Histograms are exported as count, sum, min and max.
+type ExpvarMetrics struct {
+	lock sync.Mutex
+	vars *expvar.Map
+}
+
+// make sure ExpvarMetrics implements Metrics
+var _ Metrics = new(ExpvarMetrics)
+
+// NewExpvarMetrics creates metrics published by expvar with given name, an existing map with the name is reused
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	if v, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &ExpvarMetrics{vars: v}
+	}
+
+	return &ExpvarMetrics{vars: expvar.NewMap(name)}
+}
+
+// Vars gets the published map
+func (m *ExpvarMetrics) Vars() *expvar.Map {
+	return m.vars
+}
+
+// IncCounter adds delta to counter
+func (m *ExpvarMetrics) IncCounter(name string, delta float64, labels Labels) {
+	m.vars.AddFloat(metricKey(name, labels), delta)
+}
+
+// ObserveHistogram adds value to histogram
+func (m *ExpvarMetrics) ObserveHistogram(name string, value float64, labels Labels) {
+	key := metricKey(name, labels)
+
+	m.lock.Lock()
+	h, ok := m.vars.Get(key).(*expvarHistogram)
+	if !ok {
+		h = &expvarHistogram{min: math.Inf(1), max: math.Inf(-1)}
+		m.vars.Set(key, h)
+	}
+	m.lock.Unlock()
+
+	h.observe(value)
+}
+
+// SetGauge sets gauge to value
+func (m *ExpvarMetrics) SetGauge(name string, value float64, labels Labels) {
+	key := metricKey(name, labels)
+
+	m.lock.Lock()
+	f, ok := m.vars.Get(key).(*expvar.Float)
+	if !ok {
+		f = new(expvar.Float)
+		m.vars.Set(key, f)
+	}
+	m.lock.Unlock()
+
+	f.Set(value)
+}
+
+// metricKey formats name with labels sorted by key, e.g. name{a="1",b="2"}
+func metricKey(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name + "{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k + "=" + `"` + labels[k] + `"`)
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+// expvarHistogram is a histogram exported as count, sum, min and max
+type expvarHistogram struct {
+	lock  sync.Mutex
+	count int64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (h *expvarHistogram) observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.count++
+	h.sum += value
+	h.min = math.Min(h.min, value)
+	h.max = math.Max(h.max, value)
+}
+
+// String implements expvar.Var
+func (h *expvarHistogram) String() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	lo, hi := h.min, h.max
+	if h.count == 0 {
+		lo, hi = 0, 0
+	}
+
+	bytes, _ := json.Marshal(map[string]interface{}{
+		"count": h.count,
+		"sum":   h.sum,
+		"min":   lo,
+		"max":   hi,
+	})
+
+	return string(bytes)
+}
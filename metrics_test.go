@@ -0,0 +1,101 @@
+package lunar
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testMetrics records metrics by key
+type testMetrics struct {
+	lock       sync.Mutex
+	counters   map[string]float64
+	histograms map[string][]float64
+	gauges     map[string]float64
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{
+		counters:   make(map[string]float64),
+		histograms: make(map[string][]float64),
+		gauges:     make(map[string]float64),
+	}
+}
+
+func (m *testMetrics) IncCounter(name string, delta float64, labels Labels) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.counters[metricKey(name, labels)] += delta
+}
+
+func (m *testMetrics) ObserveHistogram(name string, value float64, labels Labels) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.histograms[metricKey(name, labels)] = append(m.histograms[metricKey(name, labels)], value)
+}
+
+func (m *testMetrics) SetGauge(name string, value float64, labels Labels) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.gauges[metricKey(name, labels)] = value
+}
+
+func TestMetricKey(t *testing.T) {
+	should := require.New(t)
+
+	should.Equal("m", metricKey("m", nil))
+	should.Equal(`m{a="1",b="2"}`, metricKey("m", Labels{"b": "2", "a": "1"}))
+}
+
+func TestLabels(t *testing.T) {
+	should := require.New(t)
+
+	// labels are not built if metrics are disabled
+	app := New("myApp")
+	should.Nil(app.labels("ns"))
+	should.Zero(testing.AllocsPerRun(100, func() { app.labels("ns") }))
+
+	app = New("myApp", WithMetrics(newTestMetrics()))
+	should.Equal(Labels{"app": "myApp"}, app.labels(""))
+	should.Equal(Labels{"app": "myApp", "namespace": "ns"}, app.labels("ns.properties"))
+}
+
+func TestEndpointOf(t *testing.T) {
+	should := require.New(t)
+
+	should.Equal("configs", endpointOf("/configs/app/default/application?ip=1"))
+	should.Equal("notifications", endpointOf("/notifications/v2?appId=app"))
+	should.Equal("configfiles", endpointOf("/configfiles/json/app/default/application"))
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	should := require.New(t)
+
+	m := NewExpvarMetrics("lunar_test")
+	should.Same(m.Vars(), NewExpvarMetrics("lunar_test").Vars())
+	should.Same(m.Vars(), expvar.Get("lunar_test"))
+
+	labels := Labels{"app": "myApp"}
+	m.IncCounter(MetricLongPolls, 1, labels)
+	m.IncCounter(MetricLongPolls, 2, labels)
+	m.SetGauge(MetricLongPollConsecutiveFails, 3, labels)
+	m.SetGauge(MetricLongPollConsecutiveFails, 1, labels)
+	m.ObserveHistogram(MetricRequestDuration, 0.5, labels)
+	m.ObserveHistogram(MetricRequestDuration, 0.1, labels)
+
+	var vars map[string]interface{}
+	should.NoError(json.Unmarshal([]byte(m.Vars().String()), &vars))
+	should.Equal(float64(3), vars[`lunar_long_polls_total{app="myApp"}`])
+	should.Equal(float64(1), vars[`lunar_long_poll_consecutive_failures{app="myApp"}`])
+	should.Equal(map[string]interface{}{
+		"count": float64(2),
+		"sum":   0.6,
+		"min":   0.1,
+		"max":   0.5,
+	}, vars[`lunar_request_duration_seconds{app="myApp"}`])
+
+	should.Equal(`{"count":0,"max":0,"min":0,"sum":0}`, new(expvarHistogram).String())
+}
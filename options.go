@@ -1,6 +1,7 @@
 package lunar
 
 import (
+	"net/http"
 	"strings"
 	"time"
 )
@@ -13,6 +14,7 @@ const (
 	defaultNotificationID   = -1
 	defaultClientTimeout    = time.Second * 90
 	defaultLongPollInterval = time.Second
+	defaultNegativeCacheTTL = time.Minute
 )
 
 // Options is common options
@@ -21,8 +23,20 @@ type Options struct {
 	Cluster          string
 	AccessKeySecret  string
 	Logger           Logger
+	LogLevel         Level // logs below the level are dropped
 	ClientTimeout    time.Duration
 	LongPollInterval time.Duration
+	NegativeCacheTTL time.Duration     // how long an empty or missing namespace is remembered, 0 means never
+	Interpolation    bool              // whether to resolve placeholders like ${key} in values
+	Decryptor        Decryptor         // decrypts values like ENC(ciphertext), nil means no decryption
+	Redactor         *Redactor         // masks sensitive values in logs, nil means no redaction
+	Metrics          Metrics           // collects metrics of requests, long polls and cache, nil means no metrics
+	Tracer           Tracer            // starts spans around requests, fetches and notifications, nil means no tracing
+	HTTPClient       *http.Client      // the base http client, nil means a client with ClientTimeout
+	Transport        http.RoundTripper // the base transport, nil means the transport of HTTPClient or http.DefaultTransport
+	Middlewares      []Middleware      // wrap the transport in order
+	TLS              TLSOptions        // applied to the transport, which must be an *http.Transport
+	Authenticators   []Authenticator   // authenticate requests in order, after access key signing
 }
 
 // NewOptions creates options with defaults
@@ -32,7 +46,9 @@ func NewOptions(opts ...Option) Options {
 		Cluster:          defaultCluster,
 		ClientTimeout:    defaultClientTimeout,
 		LongPollInterval: defaultLongPollInterval,
+		NegativeCacheTTL: defaultNegativeCacheTTL,
 		Logger:           defaultLogger,
+		Redactor:         DefaultRedactor,
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -65,6 +81,13 @@ func WithLogger(logger Logger) Option {
 	}
 }
 
+// WithLogLevel sets the minimum level of logs, the default is LevelDebug which keeps all the logs
+func WithLogLevel(level Level) Option {
+	return func(o *Options) {
+		o.LogLevel = level
+	}
+}
+
 // WithClientTimeout sets client timeout
 func WithClientTimeout(timeout time.Duration) Option {
 	return func(o *Options) {
@@ -85,3 +108,108 @@ func WithAccessKeySecret(secret string) Option {
 		o.AccessKeySecret = strings.TrimSpace(secret)
 	}
 }
+
+// WithNegativeCacheTTL sets how long an empty or missing namespace is remembered without requesting apollo again,
+// 0 disables negative caching
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.NegativeCacheTTL = ttl
+	}
+}
+
+// WithInterpolation sets whether to resolve placeholders like ${key}, ${key:default}, ${ns:key} and ${env:HOME}
+// in values when reading them
+func WithInterpolation(enabled bool) Option {
+	return func(o *Options) {
+		o.Interpolation = enabled
+	}
+}
+
+// WithDecryptor sets the decryptor of values like ENC(ciphertext), values are decrypted when reading them
+// and stored encrypted in caches
+func WithDecryptor(d Decryptor) Option {
+	return func(o *Options) {
+		o.Decryptor = d
+	}
+}
+
+// WithRedactor sets the redactor which masks sensitive values in logs, nil disables redaction
+func WithRedactor(r *Redactor) Option {
+	return func(o *Options) {
+		o.Redactor = r
+	}
+}
+
+// WithMetrics sets the metrics collector of requests, long polls and cache
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+// WithTracer sets the tracer which starts spans around requests, fetches and notifications
+func WithTracer(t Tracer) Option {
+	return func(o *Options) {
+		o.Tracer = t
+	}
+}
+
+// WithHTTPClient sets the base http client of apollo client, it's copied and its own timeout is used
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = client
+	}
+}
+
+// WithTransport sets the base transport of apollo client, e.g. an http.Transport with proxy
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *Options) {
+		o.Transport = transport
+	}
+}
+
+// WithMiddleware appends middlewares which wrap the transport of apollo client,
+// the first middleware sees the request first
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *Options) {
+		o.Middlewares = append(o.Middlewares, middlewares...)
+	}
+}
+
+// WithRootCAs sets the pem files of root CAs to verify the certificate of apollo
+func WithRootCAs(files ...string) Option {
+	return func(o *Options) {
+		o.TLS.RootCAFiles = append(o.TLS.RootCAFiles, files...)
+	}
+}
+
+// WithClientCert sets the pem files of client certificate and private key for mutual tls,
+// they are reloaded when the files change
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *Options) {
+		o.TLS.CertFile = certFile
+		o.TLS.KeyFile = keyFile
+	}
+}
+
+// WithMinTLSVersion sets the minimum tls version like tls.VersionTLS12
+func WithMinTLSVersion(version uint16) Option {
+	return func(o *Options) {
+		o.TLS.MinVersion = version
+	}
+}
+
+// WithTLSServerName overrides the server name to verify the certificate of apollo
+func WithTLSServerName(name string) Option {
+	return func(o *Options) {
+		o.TLS.ServerName = name
+	}
+}
+
+// WithAuthenticator appends authenticators which add credentials to requests to apollo,
+// they run after access key signing set by WithAccessKeySecret
+func WithAuthenticator(auths ...Authenticator) Option {
+	return func(o *Options) {
+		o.Authenticators = append(o.Authenticators, auths...)
+	}
+}
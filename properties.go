@@ -0,0 +1,320 @@
+package lunar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// javaDateLayout is the layout of java.util.Date#toString
+const javaDateLayout = "Mon Jan 02 15:04:05 MST 2006"
+
+// ParseProperties reads items in java .properties format, which is used by properties namespaces and
+// the /configfiles text endpoint of apollo. It supports line continuations, = : and whitespace separators,
+// # and ! comments, and escapes like \t and \uXXXX. The input is read as utf-8.
+func ParseProperties(r io.Reader) (Items, error) {
+	items := make(Items)
+
+	br := bufio.NewReader(r)
+	for {
+		line, eof, err := readLogicalLine(br)
+		if err != nil {
+			return nil, err
+		}
+
+		if line != "" {
+			key, value, err := splitProperty(line)
+			if err != nil {
+				return nil, err
+			}
+			items[key] = value
+		}
+
+		if eof {
+			return items, nil
+		}
+	}
+}
+
+// readLogicalLine reads one logical line, natural lines ending with an odd number of backslashes
+// are joined with the following one. Comment lines and blank lines are returned as empty string.
+func readLogicalLine(br *bufio.Reader) (string, bool, error) {
+	var sb strings.Builder
+	continued := false
+
+	for {
+		line, eof, err := readNaturalLine(br)
+		if err != nil {
+			return "", false, err
+		}
+
+		line = strings.TrimLeft(line, " \t\f")
+		if !continued && (line == "" || line[0] == '#' || line[0] == '!') {
+			if eof {
+				return "", true, nil
+			}
+			continue
+		}
+
+		backslashes := 0
+		for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+			backslashes++
+		}
+
+		if backslashes%2 == 1 && !eof {
+			sb.WriteString(line[:len(line)-1])
+			continued = true
+			continue
+		}
+
+		if backslashes%2 == 1 {
+			// a trailing backslash at the end of input is dropped
+			line = line[:len(line)-1]
+		}
+		sb.WriteString(line)
+
+		return sb.String(), eof, nil
+	}
+}
+
+// readNaturalLine reads a line terminated by \n, \r or \r\n
+func readNaturalLine(br *bufio.Reader) (string, bool, error) {
+	var sb strings.Builder
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return sb.String(), true, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+
+		switch b {
+		case '\n':
+			return sb.String(), false, nil
+		case '\r':
+			if next, err := br.Peek(1); err == nil && next[0] == '\n' {
+				_, _ = br.ReadByte()
+			}
+			return sb.String(), false, nil
+		default:
+			sb.WriteByte(b)
+		}
+	}
+}
+
+// splitProperty splits a logical line into key and value
+func splitProperty(line string) (string, string, error) {
+	keyEnd := len(line)
+	valueStart := len(line)
+	hasSep := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' {
+			keyEnd, valueStart, hasSep = i, i+1, true
+			break
+		}
+		if c == ' ' || c == '\t' || c == '\f' {
+			keyEnd, valueStart = i, i+1
+			break
+		}
+	}
+
+	for valueStart < len(line) {
+		c := line[valueStart]
+		if c == ' ' || c == '\t' || c == '\f' {
+			valueStart++
+			continue
+		}
+		if !hasSep && (c == '=' || c == ':') {
+			hasSep = true
+			valueStart++
+			continue
+		}
+		break
+	}
+
+	key, err := unescapeProperty(line[:keyEnd])
+	if err != nil {
+		return "", "", err
+	}
+
+	value, err := unescapeProperty(line[valueStart:])
+	if err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
+// unescapeProperty converts escape sequences like \t, \n and \uXXXX
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var units []uint16
+	var sb strings.Builder
+
+	// flush pending utf-16 code units, which may be surrogate pairs
+	flush := func() {
+		for len(units) > 0 {
+			u := units[0]
+			units = units[1:]
+			if u >= 0xd800 && u < 0xdc00 && len(units) > 0 && units[0] >= 0xdc00 && units[0] < 0xe000 {
+				sb.WriteRune(rune(u-0xd800)<<10 | rune(units[0]-0xdc00) + 0x10000)
+				units = units[1:]
+				continue
+			}
+			sb.WriteRune(rune(u))
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			flush()
+			sb.WriteByte(c)
+			continue
+		}
+
+		i++
+		c = s[i]
+		if c == 'u' {
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("malformed \\uxxxx encoding: %q", s)
+			}
+			u, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uxxxx encoding: %q", s)
+			}
+			units = append(units, uint16(u))
+			i += 4
+			continue
+		}
+
+		flush()
+		switch c {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	flush()
+
+	return sb.String(), nil
+}
+
+// escapeProperty escapes key or value the same way as java.util.Properties#store,
+// characters out of printable ascii are written as \uXXXX
+func escapeProperty(s string, isKey bool) string {
+	var sb strings.Builder
+
+	for i, r := range s {
+		switch r {
+		case ' ':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(' ')
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\\', '=', ':', '#', '!':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0x7e {
+				writeUnicodeEscape(&sb, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func writeUnicodeEscape(sb *strings.Builder, r rune) {
+	if r > 0xffff {
+		r -= 0x10000
+		fmt.Fprintf(sb, "\\u%04X\\u%04X", 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+		return
+	}
+
+	fmt.Fprintf(sb, "\\u%04X", r)
+}
+
+// WriteProperties writes items in java .properties format with keys sorted,
+// characters out of printable ascii are escaped as \uXXXX so that java can read it
+func (items Items) WriteProperties(w io.Writer) error {
+	return writeProperties(w, items, "")
+}
+
+// writeProperties writes items in java .properties format with keys sorted,
+// comments are written at the beginning followed by a timestamp line like java does
+func writeProperties(w io.Writer, items Items, comments string) error {
+	bw := bufio.NewWriter(w)
+
+	if comments != "" {
+		for _, line := range strings.Split(comments, "\n") {
+			if _, err := bw.WriteString("#" + escapeComment(line) + "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := bw.WriteString("#" + time.Now().Format(javaDateLayout) + "\n"); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		line := escapeProperty(k, true) + "=" + escapeProperty(items[k], false) + "\n"
+		if _, err := bw.WriteString(line); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func escapeComment(s string) string {
+	var sb strings.Builder
+
+	for _, r := range strings.TrimRight(s, "\r") {
+		if r > 0x7e {
+			writeUnicodeEscape(&sb, r)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
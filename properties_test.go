@@ -0,0 +1,76 @@
+package lunar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProperties(t *testing.T) {
+	should := require.New(t)
+
+	text := "# comment\n" +
+		"! another comment\n" +
+		"\n" +
+		"a=apple\n" +
+		"  b : banana\r\n" +
+		"c cherry\r" +
+		"d\\ e=with space\n" +
+		"f=first \\\n" +
+		"    second\n" +
+		"g=\\u4F60\\u597D\\t\\uD83D\\uDE00\n" +
+		"h=\\\\\n" +
+		"i\n" +
+		"j==\n" +
+		"k=last\\"
+
+	items, err := ParseProperties(strings.NewReader(text))
+	should.NoError(err)
+
+	should.Equal(Items{
+		"a":   "apple",
+		"b":   "banana",
+		"c":   "cherry",
+		"d e": "with space",
+		"f":   "first second",
+		"g":   "你好\t😀",
+		"h":   "\\",
+		"i":   "",
+		"j":   "=",
+		"k":   "last",
+	}, items)
+
+	_, err = ParseProperties(strings.NewReader("a=\\u12"))
+	should.Error(err)
+
+	_, err = ParseProperties(strings.NewReader("a=\\uXYZW"))
+	should.Error(err)
+}
+
+func TestWriteProperties(t *testing.T) {
+	should := require.New(t)
+
+	items := Items{
+		"b":       " leading space",
+		"a":       "x=y:z#!",
+		"c d":     "line1\nline2",
+		"unicode": "你好😀",
+	}
+
+	var buf bytes.Buffer
+	should.NoError(items.WriteProperties(&buf))
+	should.Equal("a=x\\=y\\:z\\#\\!\n"+
+		"b=\\ leading space\n"+
+		"c\\ d=line1\\nline2\n"+
+		"unicode=\\u4F60\\u597D\\uD83D\\uDE00\n", buf.String())
+
+	res, err := ParseProperties(&buf)
+	should.NoError(err)
+	should.Equal(items, res)
+
+	buf.Reset()
+	should.NoError(writeProperties(&buf, items, "hello"))
+	should.True(strings.HasPrefix(buf.String(), "#hello\n#"))
+}
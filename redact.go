@@ -0,0 +1,102 @@
+package lunar
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// defaultRedactionMask replaces sensitive values
+const defaultRedactionMask = "******"
+
+// DefaultRedactionPatterns are the key patterns of sensitive values redacted by default
+var DefaultRedactionPatterns = []string{"*password*", "*passwd*", "*secret*", "*token*", "*credential*", "*private*key*"}
+
+// DefaultRedactor is the redactor used by Items.String and by default options, it should only be changed at init
+var DefaultRedactor = NewRedactor(DefaultRedactionPatterns...)
+
+var (
+	// "key": "value" in json, the value can be a string or a literal
+	jsonPairRegexp = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"|[^\s,{}\[\]"]+)`)
+	// key=value in query or properties, and key: value in yaml
+	textPairRegexp = regexp.MustCompile(`([\w.\-\[\]]+)(\s*[=:]\s*)('[^']*'|[^\s,;&'"\\]+)`)
+)
+
+// Redactor masks values of sensitive keys in items and log lines
+type Redactor struct {
+	Patterns []string // glob patterns of keys like *password*, matched case-insensitively, see path.Match for the syntax
+	Keys     []string // exact keys like db.url
+	Mask     string   // replaces the values, it's ****** if empty
+}
+
+// NewRedactor creates a redactor with given key patterns
+func NewRedactor(patterns ...string) *Redactor {
+	return &Redactor{Patterns: patterns}
+}
+
+// Match checks if the value of key should be redacted, a nil redactor matches nothing
+func (r *Redactor) Match(key string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, k := range r.Keys {
+		if k == key {
+			return true
+		}
+	}
+
+	key = strings.ToLower(key)
+	for _, p := range r.Patterns {
+		if matched, _ := path.Match(strings.ToLower(p), key); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactItems returns new items with values of sensitive keys masked
+func (r *Redactor) RedactItems(items Items) Items {
+	redacted := make(Items, len(items))
+	for k, v := range items {
+		if r.Match(k) {
+			v = r.mask()
+		}
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// RedactText masks values of sensitive keys in text like "key": "value", key=value and key: value,
+// json responses of apollo and yaml or properties content in them are covered.
+func (r *Redactor) RedactText(s string) string {
+	if r == nil {
+		return s
+	}
+
+	s = jsonPairRegexp.ReplaceAllStringFunc(s, func(pair string) string {
+		m := jsonPairRegexp.FindStringSubmatch(pair)
+		if !r.Match(m[1]) {
+			return pair
+		}
+		return `"` + m[1] + `"` + m[2] + `"` + r.mask() + `"`
+	})
+
+	return textPairRegexp.ReplaceAllStringFunc(s, func(pair string) string {
+		m := textPairRegexp.FindStringSubmatch(pair)
+		if !r.Match(m[1]) {
+			return pair
+		}
+		return m[1] + m[2] + r.mask()
+	})
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return defaultRedactionMask
+	}
+
+	return r.Mask
+}
@@ -0,0 +1,55 @@
+package lunar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	should := require.New(t)
+
+	r := NewRedactor("*password*", "*SECRET*")
+	r.Keys = []string{"db.url"}
+
+	should.True(r.Match("db.password"))
+	should.True(r.Match("DB_PASSWORD"))
+	should.True(r.Match("client.secret"))
+	should.True(r.Match("db.url"))
+	should.False(r.Match("db.url2"))
+	should.False(r.Match("db.user"))
+
+	var nilRedactor *Redactor
+	should.False(nilRedactor.Match("password"))
+	should.Equal("password=x", nilRedactor.RedactText("password=x"))
+
+	items := Items{"db.password": "p", "db.url": "u", "db.user": "root"}
+	should.Equal(Items{"db.password": "******", "db.url": "******", "db.user": "root"}, r.RedactItems(items))
+	should.Equal("p", items.Get("db.password"))
+
+	r.Mask = "xxx"
+	should.Equal(Items{"db.password": "xxx", "db.url": "xxx", "db.user": "root"}, r.RedactItems(items))
+}
+
+func TestRedactText(t *testing.T) {
+	should := require.New(t)
+
+	r := NewRedactor(DefaultRedactionPatterns...)
+
+	body := `{"appId":"SampleApp","configurations":{"db.password":"p@ss, \"word\"","db.user":"root","api.token":123,` +
+		`"content":"db:\n  password: yamlpass\n  user: root\n"},"releaseKey":"1"}`
+	should.Equal(`{"appId":"SampleApp","configurations":{"db.password":"******","db.user":"root","api.token":"******",`+
+		`"content":"db:\n  password: ******\n  user: root\n"},"releaseKey":"1"}`, r.RedactText(body))
+
+	should.Equal("user=root&password=****** secret: ******", r.RedactText("user=root&password=abc secret: 'a b'"))
+	should.Equal(`{"secrets": {"a": "b"}}`, r.RedactText(`{"secrets": {"a": "b"}}`))
+}
+
+func TestItemsStringRedacted(t *testing.T) {
+	should := require.New(t)
+
+	items := Items{"db.password": "p", "db.user": "root"}
+
+	should.Equal(`{"db":{"password":"******","user":"root"}}`, items.String())
+	should.Equal(`{"db":{"password":"p","user":"root"}}`, items.json())
+}
@@ -22,8 +22,8 @@ func sign(timestamp, pathWithQuery, secret string) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func buildHeaders(pathWithQuery, appID, secret string) map[string]string {
-	ms := time.Now().UnixNano() / int64(time.Millisecond)
+func buildHeaders(pathWithQuery, appID, secret string, now time.Time) map[string]string {
+	ms := now.UnixNano() / int64(time.Millisecond)
 	timestamp := fmt.Sprintf("%d", ms)
 	signature := sign(timestamp, pathWithQuery, secret)
 
@@ -0,0 +1,78 @@
+package lunar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errFlightPanic is returned to the callers if the shared call panics
+var errFlightPanic = errors.New("shared fetch panicked")
+
+// flightCall is an in-flight or completed call of flightGroup
+type flightCall struct {
+	done  chan struct{} // closed when the call completes
+	items Items
+	err   error
+}
+
+// flightGroup coalesces concurrent calls with the same key into one, the zero value is ready for use
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Do executes fn once for concurrent calls with the same key, every caller gets a copy of its result.
+// fn runs in its own goroutine with a context which carries the values of the first caller's context
+// but is never canceled, so a caller which gives up by its own ctx does not fail the others.
+// If fn panics, the callers get errFlightPanic.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) (Items, error)) (Items, error) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.call(detachedContext{ctx}, key, c, fn)
+	}
+	g.lock.Unlock()
+
+	select {
+	case <-c.done:
+		return c.items.Clone(), c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// call executes fn and publishes the result of c
+func (g *flightGroup) call(ctx context.Context, key string, c *flightCall, fn func(ctx context.Context) (Items, error)) {
+	// clean up even if fn panics, so that callers are not blocked forever
+	defer func() {
+		if r := recover(); r != nil {
+			c.items, c.err = nil, fmt.Errorf("%w: %v", errFlightPanic, r)
+		}
+
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+
+		close(c.done)
+	}()
+
+	c.items, c.err = fn(ctx)
+}
+
+// detachedContext keeps the values of the parent context, e.g. the span, but is never canceled
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
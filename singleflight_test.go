@@ -0,0 +1,79 @@
+package lunar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlightGroup(t *testing.T) {
+	should := require.New(t)
+
+	var g flightGroup
+	var calls int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items, err := g.Do(context.Background(), "ns", func(context.Context) (Items, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return Items{"a": "apple"}, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "apple", items.Get("a"))
+		}()
+	}
+	wg.Wait()
+
+	should.EqualValues(1, atomic.LoadInt32(&calls))
+
+	// calls after the first one completed are executed again
+	_, err := g.Do(context.Background(), "ns", func(context.Context) (Items, error) {
+		return nil, errors.New("failed")
+	})
+	should.EqualError(err, "failed")
+
+	// the callers get an error if the shared call panics
+	_, err = g.Do(context.Background(), "ns", func(context.Context) (Items, error) {
+		panic("boom")
+	})
+	should.ErrorIs(err, errFlightPanic)
+	should.ErrorContains(err, "boom")
+}
+
+func TestFlightGroupCanceled(t *testing.T) {
+	should := require.New(t)
+
+	var g flightGroup
+	release := make(chan bool)
+	var sharedErr error
+
+	type key struct{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "v"), 10*time.Millisecond)
+	defer cancel()
+
+	// the first caller gives up, the shared call goes on with the values of its context
+	_, err := g.Do(ctx, "ns", func(ctx context.Context) (Items, error) {
+		<-release
+		sharedErr = ctx.Err()
+		return Items{"a": ctx.Value(key{}).(string)}, nil
+	})
+	should.ErrorIs(err, context.DeadlineExceeded)
+
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	items, err := g.Do(context.Background(), "ns", func(context.Context) (Items, error) {
+		return nil, errors.New("should share the call in flight")
+	})
+	should.NoError(err)
+	should.Equal("v", items.Get("a"))
+	should.NoError(sharedErr)
+}
@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package lunar
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SlogLogger is an adapter of log/slog, it implements both Logger and StructuredLogger
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// make sure SlogLogger implements Logger and StructuredLogger
+var _ Logger = new(SlogLogger)
+var _ StructuredLogger = new(SlogLogger)
+
+// NewSlogLogger creates an adapter of given slog logger, slog.Default() is used if it's nil
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return &SlogLogger{Logger: l}
+}
+
+// Printf writes an info log
+func (l *SlogLogger) Printf(format string, args ...interface{}) {
+	l.Logger.Info(fmt.Sprintf(format, args...))
+}
+
+// Log writes a log with fields as attributes
+func (l *SlogLogger) Log(level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+
+	l.Logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+// slogLevel converts level to slog level
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	}
+
+	return slog.LevelError
+}
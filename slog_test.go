@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package lunar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	should := require.New(t)
+
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	o := NewOptions(WithLogger(l))
+	o.log(LevelError, "fail to get data", F("app", "SampleApp"), F("status", 500), F("error", errors.New("password=abc")))
+
+	var record map[string]interface{}
+	should.NoError(json.Unmarshal(buf.Bytes(), &record))
+	should.Equal("ERROR", record["level"])
+	should.Equal("fail to get data", record["msg"])
+	should.Equal("SampleApp", record["app"])
+	should.Equal(float64(500), record["status"])
+	should.Equal("password=******", record["error"])
+
+	buf.Reset()
+	l.Printf("hello %s", "world")
+	should.Contains(buf.String(), `"level":"INFO","msg":"hello world"`)
+
+	should.NotNil(NewSlogLogger(nil).Logger)
+}
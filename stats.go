@@ -0,0 +1,158 @@
+package lunar
+
+import (
+	"sync"
+	"time"
+)
+
+// CacheStats is the statistics of a cache
+type CacheStats struct {
+	Hits       int64                     // number of reads which found items
+	Misses     int64                     // number of reads which found nothing
+	Sets       int64                     // number of writes
+	Deletes    int64                     // number of deleted namespaces
+	Namespaces map[string]NamespaceStats // key: namespace
+}
+
+// NamespaceStats is the statistics of a namespace in cache
+type NamespaceStats struct {
+	Size      int       // number of items
+	UpdatedAt time.Time // last time the namespace is written, zero if it's only read
+}
+
+// CacheStatsProvider is implemented by caches which collect statistics
+type CacheStatsProvider interface {
+	CacheStats() CacheStats
+}
+
+// statsRecorder collects cache statistics, the zero value is ready for use
+type statsRecorder struct {
+	lock       sync.Mutex
+	stats      CacheStats
+	namespaces map[string]NamespaceStats
+}
+
+func (r *statsRecorder) recordGet(namespace string, items Items) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(items) == 0 {
+		r.stats.Misses++
+		return
+	}
+
+	r.stats.Hits++
+
+	if r.namespaces == nil {
+		r.namespaces = make(map[string]NamespaceStats)
+	}
+	ns := r.namespaces[namespace]
+	ns.Size = len(items)
+	r.namespaces[namespace] = ns
+}
+
+func (r *statsRecorder) recordSet(namespace string, items Items) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stats.Sets++
+
+	if r.namespaces == nil {
+		r.namespaces = make(map[string]NamespaceStats)
+	}
+	r.namespaces[namespace] = NamespaceStats{
+		Size:      len(items),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (r *statsRecorder) recordDelete(namespaces ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, namespace := range namespaces {
+		r.stats.Deletes++
+		delete(r.namespaces, namespace)
+	}
+}
+
+// CacheStats gets a copy of the statistics
+func (r *statsRecorder) CacheStats() CacheStats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	stats := r.stats
+	stats.Namespaces = make(map[string]NamespaceStats, len(r.namespaces))
+	for k, v := range r.namespaces {
+		stats.Namespaces[k] = v
+	}
+
+	return stats
+}
+
+// StatsCache is a wrapper which adds statistics to any cache
+type StatsCache struct {
+	Cache
+	stats statsRecorder
+}
+
+// make sure StatsCache implements Cache, CacheStatsProvider and CacheViewer
+var _ Cache = new(StatsCache)
+var _ CacheStatsProvider = new(StatsCache)
+var _ CacheViewer = new(StatsCache)
+
+// NewStatsCache wraps c with statistics
+func NewStatsCache(c Cache) *StatsCache {
+	return &StatsCache{Cache: c}
+}
+
+// GetItems gets items from cache
+func (c *StatsCache) GetItems(namespace string) Items {
+	items := c.Cache.GetItems(namespace)
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// ViewItems gets items from cache without copying if the wrapped cache implements CacheViewer,
+// the items must not be modified
+func (c *StatsCache) ViewItems(namespace string) Items {
+	items := viewItems(c.Cache, namespace)
+	c.stats.recordGet(namespace, items)
+
+	return items
+}
+
+// SetItems sets items into cache
+func (c *StatsCache) SetItems(namespace string, items Items) error {
+	if err := c.Cache.SetItems(namespace, items); err != nil {
+		return err
+	}
+
+	c.stats.recordSet(namespace, items)
+
+	return nil
+}
+
+// Delete deletes given namespace
+func (c *StatsCache) Delete(namespace string) error {
+	if err := c.Cache.Delete(namespace); err != nil {
+		return err
+	}
+
+	c.stats.recordDelete(namespace)
+
+	return nil
+}
+
+// Drain deletes the whole cache
+func (c *StatsCache) Drain() {
+	keys := c.Cache.GetKeys()
+	c.Cache.Drain()
+	c.stats.recordDelete(keys...)
+}
+
+// CacheStats gets the statistics of cache
+func (c *StatsCache) CacheStats() CacheStats {
+	return c.stats.CacheStats()
+}
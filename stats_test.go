@@ -0,0 +1,37 @@
+package lunar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsCache(t *testing.T) {
+	should := require.New(t)
+
+	// hide the statistics of MemoryCache
+	custom := struct{ Cache }{new(MemoryCache)}
+
+	cache := NewStatsCache(custom)
+	should.Len(cache.GetItems("ns"), 0)
+
+	should.NoError(cache.SetItems("ns", Items{"a": "apple", "b": "banana"}))
+	should.NoError(cache.SetItems("ns.txt", Items{"content": "plaintext"}))
+	should.Equal("apple", cache.GetItems("ns").Get("a"))
+
+	stats := cache.CacheStats()
+	should.EqualValues(1, stats.Hits)
+	should.EqualValues(1, stats.Misses)
+	should.EqualValues(2, stats.Sets)
+	should.EqualValues(0, stats.Deletes)
+	should.Len(stats.Namespaces, 2)
+	should.Equal(2, stats.Namespaces["ns"].Size)
+	should.False(stats.Namespaces["ns"].UpdatedAt.IsZero())
+
+	should.NoError(cache.Delete("ns"))
+	cache.Drain()
+
+	stats = cache.CacheStats()
+	should.EqualValues(2, stats.Deletes)
+	should.Len(stats.Namespaces, 0)
+}
@@ -0,0 +1,160 @@
+package lunar
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSOptions is the tls configuration of connections to apollo
+type TLSOptions struct {
+	RootCAFiles []string // pem files of root CAs, the system pool is used if empty
+	CertFile    string   // pem file of client certificate, it's reloaded when the file changes
+	KeyFile     string   // pem file of client private key
+	MinVersion  uint16   // minimum tls version like tls.VersionTLS12, 0 means the default of crypto/tls
+	ServerName  string   // overrides the server name to verify the certificate of apollo
+}
+
+// enabled checks if any tls option is set
+func (t TLSOptions) enabled() bool {
+	return len(t.RootCAFiles) > 0 || t.CertFile != "" || t.KeyFile != "" || t.MinVersion != 0 || t.ServerName != ""
+}
+
+// newTLSConfig creates tls config from options, the config of transport is kept unless it's overridden by options
+func (t TLSOptions) newTLSConfig(base *tls.Config) (*tls.Config, error) {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	if t.MinVersion != 0 {
+		config.MinVersion = t.MinVersion
+	}
+	if t.ServerName != "" {
+		config.ServerName = t.ServerName
+	}
+
+	if len(t.RootCAFiles) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range t.RootCAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in %s", file)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		reloader, err := newCertReloader(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	return config, nil
+}
+
+// applyTLS returns a copy of transport with tls options applied, nil transport means http.DefaultTransport.
+// The options require an *http.Transport, because the tls config of other transports is unknown.
+// If the options can not be applied, the error is returned along with a transport which fails every request with it,
+// so that requests are never sent without the tls options.
+func (t TLSOptions) applyTLS(transport http.RoundTripper) (http.RoundTripper, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return failingTransport(fmt.Errorf("invalid tls options: transport %T is not *http.Transport", transport))
+	}
+
+	cloned := base.Clone()
+	config, err := t.newTLSConfig(cloned.TLSClientConfig)
+	if err != nil {
+		return failingTransport(fmt.Errorf("invalid tls options: %w", err))
+	}
+	cloned.TLSClientConfig = config
+
+	return cloned, nil
+}
+
+// failingTransport returns a transport which fails every request with err, and err itself
+func failingTransport(err error) (http.RoundTripper, error) {
+	return RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, err
+	}), err
+}
+
+// certReloader loads client certificate and reloads it when the files are changed, e.g. rotated by cert-manager
+type certReloader struct {
+	lock     sync.Mutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time // the latest modification time of cert and key files
+	size     int64     // the total size of cert and key files
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// load loads the certificate if the files are changed since last load
+func (r *certReloader) load() (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var modTime time.Time
+	var size int64
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return r.fallback(err)
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		size += info.Size()
+	}
+
+	if r.cert != nil && modTime.Equal(r.modTime) && size == r.size {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// the files may be in the middle of rotation
+		return r.fallback(err)
+	}
+
+	r.cert, r.modTime, r.size = &cert, modTime, size
+
+	return r.cert, nil
+}
+
+// fallback returns the certificate loaded before if any
+func (r *certReloader) fallback(err error) (*tls.Certificate, error) {
+	if r.cert != nil {
+		return r.cert, nil
+	}
+
+	return nil, err
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.load()
+}
@@ -0,0 +1,203 @@
+package lunar
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate with its private key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA if parent is nil
+func newTestCert(t *testing.T, parent *testCert, name string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	require.NoError(t, err)
+
+	return cert
+}
+
+// writeClientCert writes cert and key files, the modification time is set to make the change visible
+func writeClientCert(t *testing.T, c *testCert, certFile, keyFile string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(certFile, c.certPEM(), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, c.keyPEM(t), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func TestMutualTLS(t *testing.T) {
+	should := require.New(t)
+
+	dir := t.TempDir()
+	ca := newTestCert(t, nil, "lunar-ca")
+	caFile := filepath.Join(dir, "ca.pem")
+	should.NoError(os.WriteFile(caFile, ca.certPEM(), 0o600))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	var clientName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+		// handshake again for every request to see the rotated certificate
+		w.Header().Set("Connection", "close")
+		w.Write([]byte(`{"appId":"myApp","namespaceName":"application","configurations":{"a":"1"}}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{newTestCert(t, ca, "apollo.local").tlsCertificate(t)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	writeClientCert(t, newTestCert(t, ca, "first"), certFile, keyFile, time.Now().Add(-time.Minute))
+
+	client := NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithRootCAs(caFile),
+		WithClientCert(certFile, keyFile),
+		WithTLSServerName("apollo.local"),
+		WithMinTLSVersion(tls.VersionTLS12),
+	)
+
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal("1", ns.Items.Get("a"))
+	should.Equal("first", clientName)
+
+	// rotated
+	writeClientCert(t, newTestCert(t, ca, "second"), certFile, keyFile, time.Now())
+
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal("second", clientName)
+
+	// broken files in the middle of rotation, the last certificate is used
+	should.NoError(os.WriteFile(keyFile, []byte("broken"), 0o600))
+
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal("second", clientName)
+
+	// server name does not match the certificate
+	client = NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithRootCAs(caFile),
+		WithClientCert(certFile, keyFile),
+	)
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.Error(err)
+
+	// unknown CA
+	client = NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithTLSServerName("apollo.local"),
+	)
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.Error(err)
+}
+
+func TestTLSOptions(t *testing.T) {
+	should := require.New(t)
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.pem")
+	should.NoError(os.WriteFile(invalid, []byte("not a certificate"), 0o600))
+
+	client := NewApolloClient("myApp", WithServer("localhost:8080"), WithRootCAs(invalid))
+	_, err := client.GetNamespace(defaultNamespace, "")
+	should.ErrorContains(err, "invalid tls options")
+
+	client = NewApolloClient("myApp", WithServer("localhost:8080"), WithClientCert(filepath.Join(dir, "missing.pem"), invalid))
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.ErrorContains(err, "invalid tls options")
+
+	// the transport is copied with tls config
+	transport := &http.Transport{MaxIdleConns: 3, TLSClientConfig: &tls.Config{ServerName: "apollo.local"}}
+	client = NewApolloClient("myApp", WithTransport(transport), WithMinTLSVersion(tls.VersionTLS13))
+	applied, ok := client.Client.Transport.(*http.Transport)
+	should.True(ok)
+	should.NotSame(transport, applied)
+	should.Equal(3, applied.MaxIdleConns)
+	should.Equal(uint16(tls.VersionTLS13), applied.TLSClientConfig.MinVersion)
+	should.Equal("apollo.local", applied.TLSClientConfig.ServerName)
+	should.Zero(transport.TLSClientConfig.MinVersion)
+
+	// transports other than http.Transport can not be used with tls options, requests fail rather than go without them
+	var logs []string
+	custom := RoundTripperFunc(http.DefaultTransport.RoundTrip)
+	client = NewApolloClient("myApp",
+		WithServer("localhost:8080"),
+		WithTransport(custom),
+		WithMinTLSVersion(tls.VersionTLS13),
+		WithLogger(LoggerFunc(func(format string, args ...interface{}) {
+			logs = append(logs, fmt.Sprintf(format, args...))
+		})),
+	)
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.ErrorContains(err, "transport lunar.RoundTripperFunc is not *http.Transport")
+	should.NotEmpty(logs)
+	should.Contains(logs[0], "[ERROR] fail to apply tls options")
+}
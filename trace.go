@@ -0,0 +1,39 @@
+package lunar
+
+import "context"
+
+// names of spans
+const (
+	SpanRequest                = "lunar.request"                // a request to apollo, attributes: app, cluster, endpoint, status
+	SpanGetNamespaceFromApollo = "lunar.GetNamespaceFromApollo" // attributes: app, cluster, namespace, release_key
+	SpanLongPoll               = "lunar.longPoll"               // a round of long poll, attributes: app, cluster, notifications
+	SpanNotify                 = "lunar.notify"                 // dispatch of a notification to watcher, attributes: app, cluster, namespace, notification_id
+)
+
+// Tracer starts spans, it can be bridged to tracing systems like OpenTelemetry
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, the returned context carries the new span
+	Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span)
+}
+
+// Span is a unit of work in a trace
+type Span interface {
+	SetAttributes(attrs ...Field)
+	// End ends the span, err is nil if the work succeeds
+	End(err error)
+}
+
+// noopSpan is used when there is no tracer
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Field) {}
+func (noopSpan) End(error)              {}
+
+// startSpan starts a span by the tracer, it does nothing if there is no tracer
+func (o *Options) startSpan(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	if o.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return o.Tracer.Start(ctx, name, attrs...)
+}
@@ -0,0 +1,217 @@
+package lunar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+)
+
+type testSpanKey struct{}
+
+// testSpan records a span
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...Field) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *testSpan) End(err error) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+
+	s.err = err
+	s.ended = true
+}
+
+// testTracer records spans in order of start
+type testTracer struct {
+	lock  sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	span := &testSpan{tracer: t, name: name, attrs: make(map[string]interface{})}
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent.name
+	}
+	for _, attr := range attrs {
+		span.attrs[attr.Key] = attr.Value
+	}
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (t *testTracer) find(name string) []*testSpan {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var spans []*testSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	should := require.New(t)
+	defer gock.Off()
+
+	tracer := new(testTracer)
+	app := New("TracingApp", WithTracer(tracer))
+
+	gock.New(app.Server).
+		Get("/configs/TracingApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"TracingApp","namespaceName":"application","configurations":{"a":"1"},"releaseKey":"1"}`)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	v, err := app.GetValueInNamespaceContext(ctx, "a", defaultNamespace)
+	root.End(nil)
+
+	should.NoError(err)
+	should.Equal("1", v)
+
+	fetches := tracer.find(SpanGetNamespaceFromApollo)
+	should.Len(fetches, 1)
+	should.Equal("root", fetches[0].parent)
+	should.Equal(defaultNamespace, fetches[0].attrs["namespace"])
+	should.Equal("1", fetches[0].attrs["release_key"])
+	should.True(fetches[0].ended)
+	should.NoError(fetches[0].err)
+
+	requests := tracer.find(SpanRequest)
+	should.Len(requests, 1)
+	should.Equal(SpanGetNamespaceFromApollo, requests[0].parent)
+	should.Equal("configs", requests[0].attrs["endpoint"])
+	should.Equal(http.StatusOK, requests[0].attrs["status"])
+
+	// long poll and notification
+	gock.New(app.Server).
+		Get("/notifications/v2").
+		Reply(http.StatusOK).
+		BodyString(`[{"namespaceName":"application","notificationId":2}]`)
+	gock.New(app.Server).
+		Get("/configs/TracingApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"TracingApp","namespaceName":"application","configurations":{"a":"2"},"releaseKey":"2"}`)
+
+	go func() { <-app.watchChan }()
+	app.longPoll(context.Background())
+
+	polls := tracer.find(SpanLongPoll)
+	should.Len(polls, 1)
+	should.Equal(1, polls[0].attrs["notifications"])
+	should.True(polls[0].ended)
+
+	fetches = tracer.find(SpanGetNamespaceFromApollo)
+	should.Len(fetches, 2)
+	should.Equal(SpanLongPoll, fetches[1].parent)
+
+	notifies := tracer.find(SpanNotify)
+	should.Len(notifies, 1)
+	should.Equal(SpanLongPoll, notifies[0].parent)
+	should.Equal(2, notifies[0].attrs["notification_id"])
+	should.True(notifies[0].ended)
+	should.NoError(notifies[0].err)
+
+	// failed request
+	gock.New(app.Server).
+		Get("/configs/TracingApp/default/missing").
+		ReplyError(context.DeadlineExceeded)
+
+	_, err = app.GetNamespaceFromApollo("missing")
+	should.Error(err)
+
+	fetches = tracer.find(SpanGetNamespaceFromApollo)
+	should.Len(fetches, 3)
+	should.Error(fetches[2].err)
+}
+
+func TestWatchContext(t *testing.T) {
+	should := require.New(t)
+	defer gock.Off()
+
+	app := New("WatchContextApp", WithLongPollInterval(time.Hour))
+
+	gock.New(app.Server).
+		Get("/configs/WatchContextApp/default/application").
+		Reply(http.StatusOK).
+		BodyString(`{"appId":"WatchContextApp","namespaceName":"application","configurations":{"a":"1"},"releaseKey":"1"}`)
+
+	var lines []string
+	var lock sync.Mutex
+	app.UseLogger(LoggerFunc(func(format string, args ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.WatchContext(ctx)
+	cancel()
+
+	should.Eventually(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, line := range lines {
+			if strings.HasPrefix(line, "[INFO] stop watching") && strings.Contains(line, "context canceled") {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFetchSharedByCanceledCaller(t *testing.T) {
+	should := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"appId":"myApp","namespaceName":"application","configurations":{"a":"1"},"releaseKey":"1"}`))
+	}))
+	defer server.Close()
+
+	app := New("myApp", WithServer(server.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	errs := make(chan error)
+	go func() {
+		_, err := app.GetValueInNamespaceContext(ctx, "a", defaultNamespace)
+		errs <- err
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	v, err := app.GetValueInNamespaceContext(context.Background(), "a", defaultNamespace)
+	should.NoError(err)
+	should.Equal("1", v)
+	should.ErrorIs(<-errs, context.DeadlineExceeded)
+}
@@ -0,0 +1,64 @@
+package lunar
+
+import "net/http"
+
+// Middleware wraps a RoundTripper to add behaviors like headers, request ids, gzip or fault injection
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a bridge between http.RoundTripper and a function
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper interface
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// HeaderMiddleware sets a header on every request, e.g. User-Agent
+func HeaderMiddleware(key, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// a RoundTripper should not modify the request
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// newHTTPClient creates the http client of apollo client from options. The client set by WithHTTPClient is copied
+// and used with its own timeout, otherwise a client with ClientTimeout is created. The transport is the one set by
+// WithTransport, or the one of the client, or http.DefaultTransport, it's copied with tls options applied if any,
+// and then wrapped by middlewares in order, so the first middleware sees the request first.
+// The tls options require the transport to be an *http.Transport, otherwise every request fails.
+// Both normal requests and long polls use this client.
+func (o *Options) newHTTPClient() *http.Client {
+	client := &http.Client{Timeout: o.ClientTimeout}
+	if o.HTTPClient != nil {
+		copied := *o.HTTPClient
+		client = &copied
+	}
+
+	transport := o.Transport
+	if transport == nil {
+		transport = client.Transport
+	}
+	if o.TLS.enabled() {
+		var err error
+		if transport, err = o.TLS.applyTLS(transport); err != nil {
+			o.log(LevelError, "fail to apply tls options", F("error", err))
+		}
+	}
+	if transport == nil && len(o.Middlewares) > 0 {
+		// http.DefaultTransport is resolved when sending requests, like a client without transport does
+		transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return http.DefaultTransport.RoundTrip(req)
+		})
+	}
+
+	for i := len(o.Middlewares) - 1; i >= 0; i-- {
+		transport = o.Middlewares[i](transport)
+	}
+
+	client.Transport = transport
+
+	return client
+}
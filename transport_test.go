@@ -0,0 +1,79 @@
+package lunar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	should := require.New(t)
+
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Write([]byte(`{"appId":"myApp","namespaceName":"application","configurations":{"a":"1"}}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	client := NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithMiddleware(trace("first"), HeaderMiddleware("User-Agent", "lunar")),
+		WithMiddleware(trace("second"), HeaderMiddleware("X-Request-Id", "1")),
+	)
+
+	ns, err := client.GetNamespace(defaultNamespace, "")
+	should.NoError(err)
+	should.Equal("1", ns.Items.Get("a"))
+	should.Equal([]string{"first", "second"}, order)
+	should.Equal("lunar", headers.Get("User-Agent"))
+	should.Equal("1", headers.Get("X-Request-Id"))
+
+	// fault injection
+	client = NewApolloClient("myApp",
+		WithServer(server.URL),
+		WithMiddleware(func(http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("injected")
+			})
+		}),
+	)
+
+	_, err = client.GetNamespace(defaultNamespace, "")
+	should.ErrorContains(err, "injected")
+}
+
+func TestHTTPClientAndTransport(t *testing.T) {
+	should := require.New(t)
+
+	client := NewApolloClient("myApp", WithClientTimeout(time.Second))
+	should.Equal(time.Second, client.Client.Timeout)
+	should.Nil(client.Client.Transport)
+
+	transport := &http.Transport{}
+	base := &http.Client{Timeout: time.Minute, Transport: transport}
+
+	client = NewApolloClient("myApp", WithHTTPClient(base), WithMiddleware(HeaderMiddleware("a", "b")))
+	should.NotSame(base, client.Client)
+	should.Equal(time.Minute, client.Client.Timeout)
+	should.Same(transport, base.Transport) // the base client is not modified
+	should.IsType(RoundTripperFunc(nil), client.Client.Transport)
+
+	other := &http.Transport{}
+	client = NewApolloClient("myApp", WithHTTPClient(base), WithTransport(other))
+	should.Same(other, client.Client.Transport)
+}
@@ -1,23 +1,35 @@
 package lunar
 
-// Node is a tree node
+import "fmt"
+
+// Node is a tree node, children are kept in insertion order and indexed by name.
+// Children should be added by AddChildren so that the index is maintained, reads like GetChild and Find never modify
+// the node, so they are safe for concurrent use.
 type Node struct {
 	Name     string
 	Value    string // value only makes sense in leaf
 	Children []*Node
+	hasValue bool           // the value is set explicitly, a parent with value is a conflict when expanding items
+	index    map[string]int // key: name of child, value: position in Children
 }
 
 // AddChildren adds children node if it's not existing
 func (node *Node) AddChildren(children ...*Node) {
 	for _, child := range children {
-		if child != nil && node.GetChild(child.Name) == nil {
+		if child != nil && node.childIndex(child.Name) < 0 {
+			node.index[child.Name] = len(node.Children)
 			node.Children = append(node.Children, child)
 		}
 	}
 }
 
-// GetChild gets a child by its name
+// GetChild gets a child by its name. Children which are not indexed, e.g. set by a Children literal
+// or replaced directly, are found by a linear scan.
 func (node *Node) GetChild(name string) *Node {
+	if i, ok := node.index[name]; ok && i < len(node.Children) && node.Children[i].Name == name {
+		return node.Children[i]
+	}
+
 	for _, child := range node.Children {
 		if child.Name == name {
 			return child
@@ -27,6 +39,197 @@ func (node *Node) GetChild(name string) *Node {
 	return nil
 }
 
+// child gets a child by its name like GetChild, but it maintains the index, so it's for the methods which modify the node
+func (node *Node) child(name string) *Node {
+	if i := node.childIndex(name); i >= 0 {
+		return node.Children[i]
+	}
+
+	return nil
+}
+
+// childIndex gets the position of a child by its name, -1 if not found.
+// It rebuilds the index if Children are modified directly, so it's only called by the methods which modify the node.
+func (node *Node) childIndex(name string) int {
+	if node.index == nil || len(node.index) != len(node.Children) {
+		node.reindex()
+	}
+
+	i, ok := node.index[name]
+	if ok && (i >= len(node.Children) || node.Children[i].Name != name) {
+		node.reindex()
+		i, ok = node.index[name]
+	}
+
+	if !ok {
+		return -1
+	}
+
+	return i
+}
+
+// reindex rebuilds the index of children, the first child wins if names are duplicated
+func (node *Node) reindex() {
+	node.index = make(map[string]int, len(node.Children))
+	for i, child := range node.Children {
+		if _, ok := node.index[child.Name]; !ok {
+			node.index[child.Name] = i
+		}
+	}
+}
+
+// Find finds the descendant at given path like a.b.c or servers[0].host, an empty path is the node itself.
+// It returns nil if not found.
+func (node *Node) Find(path string) *Node {
+	if path == "" {
+		return node
+	}
+
+	return node.find(splitItemKey(path))
+}
+
+func (node *Node) find(names []string) *Node {
+	for _, name := range names {
+		if node = node.GetChild(name); node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
+
+// Walk calls fn for the node and all its descendants in depth-first order, children are visited in their order.
+// The path of the node itself is empty, paths of descendants are names joined by dot, e.g. servers.0.host.
+func (node *Node) Walk(fn func(path string, node *Node)) {
+	node.walk("", fn)
+}
+
+func (node *Node) walk(path string, fn func(path string, node *Node)) {
+	fn(path, node)
+
+	for _, child := range node.Children {
+		child.walk(joinPath(path, child.Name), fn)
+	}
+}
+
+// Merge merges a copy of other into the node, values of other override values of the node.
+// A key which becomes both a value and a parent is handled by policy, like Items.ExpandWithPolicy does.
+// The node may be partially merged if an error is returned.
+func (node *Node) Merge(other *Node, policy ConflictPolicy) error {
+	return node.merge(other, "", policy)
+}
+
+func (node *Node) merge(other *Node, path string, policy ConflictPolicy) error {
+	hasValue := node.hasValue || node.IsLeaf()
+
+	if other.hasValue || other.IsLeaf() {
+		node.Value = other.Value
+		hasValue = true
+	}
+
+	for _, child := range other.Children {
+		existing := node.child(child.Name)
+		if existing == nil {
+			node.AddChildren(child.clone())
+			continue
+		}
+		if err := existing.merge(child, joinPath(path, child.Name), policy); err != nil {
+			return err
+		}
+	}
+
+	node.hasValue = hasValue
+	if path == "" || !hasValue || node.IsLeaf() {
+		return nil
+	}
+
+	switch policy {
+	case ConflictLeafWins:
+		node.Children = nil
+		node.index = nil
+	case ConflictError:
+		return fmt.Errorf("key %s is both a value and a parent", path)
+	}
+
+	return nil
+}
+
+// clone makes a deep copy of the node
+func (node *Node) clone() *Node {
+	clone := &Node{
+		Name:     node.Name,
+		Value:    node.Value,
+		hasValue: node.hasValue,
+	}
+
+	for _, child := range node.Children {
+		clone.AddChildren(child.clone())
+	}
+
+	return clone
+}
+
+// Sub gets the descendants of the node at given prefix as items, keys are relative to the prefix like Items.Sub.
+// Lists are flattened to brackets like Flatten does, except the index of a list at the prefix, e.g. 0.host,
+// and the value of the prefix itself is dropped.
+func (node *Node) Sub(prefix string) Items {
+	sub := node.Find(prefix)
+	if sub == nil || sub.IsLeaf() {
+		return make(Items)
+	}
+
+	tree, _ := expandNode(sub, "", ConflictValueKey)
+
+	items := Flatten(tree)
+	if _, ok := tree.([]interface{}); ok {
+		unbracketed := make(Items, len(items))
+		for k, v := range items {
+			unbracketed[unbracketIndex(k)] = v
+		}
+		items = unbracketed
+	}
+
+	return items
+}
+
+// Delete deletes the descendant at given path, it returns false if not found.
+// Parents which become empty are deleted as well unless they have their own values,
+// so that they are not left as empty leaves.
+func (node *Node) Delete(path string) bool {
+	if path == "" {
+		return false
+	}
+
+	names := splitItemKey(path)
+
+	// the nodes along the path, parents[i] is the parent of names[i]
+	parents := make([]*Node, len(names))
+	parent := node
+	for i, name := range names {
+		parents[i] = parent
+		if parent = parent.GetChild(name); parent == nil {
+			return false
+		}
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		parents[i].removeChild(names[i])
+		if i == 0 || !parents[i].IsLeaf() || parents[i].hasValue {
+			break
+		}
+	}
+
+	return true
+}
+
+// removeChild removes a child by its name
+func (node *Node) removeChild(name string) {
+	if i := node.childIndex(name); i >= 0 {
+		node.Children = append(node.Children[:i], node.Children[i+1:]...)
+		node.reindex()
+	}
+}
+
 // IsLeaf returns true if the node is a leaf
 func (node *Node) IsLeaf() bool {
 	return len(node.Children) == 0
@@ -45,3 +248,12 @@ func (node *Node) ToMap() interface{} {
 
 	return m
 }
+
+// joinPath joins path and name by dot
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
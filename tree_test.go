@@ -1,8 +1,10 @@
 package lunar
 
 import (
+	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,3 +49,187 @@ func TestTree(t *testing.T) {
 	should := require.New(t)
 	should.Equal(m, n0.ToMap())
 }
+
+func TestTreeChildrenIndex(t *testing.T) {
+	should := require.New(t)
+
+	root := &Node{Name: "root"}
+	for _, name := range []string{"c", "a", "b", "a"} {
+		root.AddChildren(&Node{Name: name, Value: name})
+	}
+
+	// children keep insertion order and names are unique
+	should.Len(root.Children, 3)
+	should.Equal("c", root.Children[0].Name)
+	should.Equal("a", root.Children[1].Name)
+	should.Equal("b", root.Children[2].Name)
+	should.Equal("b", root.GetChild("b").Value)
+	should.Nil(root.GetChild("d"))
+
+	// children modified directly are reindexed
+	root.Children = root.Children[1:]
+	should.Nil(root.GetChild("c"))
+	should.Equal("a", root.GetChild("a").Value)
+
+	root.Children[0], root.Children[1] = root.Children[1], root.Children[0]
+	should.Equal("a", root.GetChild("a").Value)
+	should.Equal("b", root.GetChild("b").Value)
+
+	// a child replaced in place
+	root.Children[0] = &Node{Name: "x", Value: "x"}
+	should.Equal("x", root.GetChild("x").Value)
+	should.Nil(root.GetChild("b"))
+	root.AddChildren(&Node{Name: "x"})
+	should.Len(root.Children, 2)
+
+	// reads of children literal are safe for concurrent use
+	literal := &Node{Children: []*Node{{Name: "a", Children: []*Node{{Name: "b", Value: "1"}}}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "1", literal.Find("a.b").Value)
+			assert.Nil(t, literal.GetChild("c"))
+		}()
+	}
+	wg.Wait()
+	should.Nil(literal.index)
+}
+
+func TestTreeFind(t *testing.T) {
+	should := require.New(t)
+
+	root := Items{"a.b.c": "1", "servers[0].host": "s1", "servers[1].host": "s2"}.Tree()
+
+	should.Same(root, root.Find(""))
+	should.Equal("1", root.Find("a.b.c").Value)
+	should.Equal("s2", root.Find("servers[1].host").Value)
+	should.Equal("s2", root.Find("servers.1.host").Value)
+	should.Len(root.Find("a.b").Children, 1)
+	should.Nil(root.Find("a.c"))
+	should.Nil(root.Find("a.b.c.d"))
+}
+
+func TestTreeWalk(t *testing.T) {
+	should := require.New(t)
+
+	root := Items{"b": "2", "a.y": "1", "a.x": "0"}.Tree()
+
+	var paths []string
+	root.Walk(func(path string, node *Node) {
+		if node.IsLeaf() {
+			paths = append(paths, path+"="+node.Value)
+		} else {
+			paths = append(paths, path)
+		}
+	})
+
+	should.Equal([]string{"", "a", "a.x=0", "a.y=1", "b=2"}, paths)
+}
+
+func TestTreeMerge(t *testing.T) {
+	should := require.New(t)
+
+	base := Items{"db.host": "localhost", "db.port": "3306", "log": "info"}
+	overlay := Items{"db.host": "10.0.0.1", "db.user": "root"}
+
+	root := base.Tree()
+	other := overlay.Tree()
+	should.NoError(root.Merge(other, ConflictValueKey))
+	should.Equal(Items{
+		"db.host": "10.0.0.1",
+		"db.port": "3306",
+		"db.user": "root",
+		"log":     "info",
+	}, root.Sub(""))
+
+	// other is copied rather than shared
+	other.Find("db.user").Value = "admin"
+	should.Equal("root", root.Find("db.user").Value)
+
+	// conflicts
+	root = base.Tree()
+	should.NoError(root.Merge(Items{"log.level": "debug"}.Tree(), ConflictValueKey))
+	should.Equal(map[string]interface{}{"_value": "info", "level": "debug"}, root.Sub("").Expand().(map[string]interface{})["log"])
+
+	root = base.Tree()
+	should.NoError(root.Merge(Items{"log.level": "debug"}.Tree(), ConflictLeafWins))
+	should.Equal("info", root.Find("log").Value)
+	should.True(root.Find("log").IsLeaf())
+
+	root = base.Tree()
+	should.NoError(root.Merge(Items{"db": "mysql"}.Tree(), ConflictLeafWins))
+	should.Equal("mysql", root.Find("db").Value)
+	should.True(root.Find("db").IsLeaf())
+
+	root = base.Tree()
+	should.EqualError(root.Merge(Items{"db": "mysql"}.Tree(), ConflictError), "key db is both a value and a parent")
+}
+
+func TestTreeSub(t *testing.T) {
+	should := require.New(t)
+
+	root := Items{
+		"db":                "mysql",
+		"db.host":           "localhost",
+		"db.replicas[0]":    "r0",
+		"db.replicas[1]":    "r1",
+		"db.pool.max":       "10",
+		"servers[0].host":   "s1",
+		"servers[1].host":   "s2",
+		"servers[1].weight": "2",
+	}.Tree()
+
+	should.Equal(Items{
+		"host":        "localhost",
+		"replicas[0]": "r0",
+		"replicas[1]": "r1",
+		"pool.max":    "10",
+	}, root.Sub("db"))
+	should.Equal(Items{"max": "10"}, root.Sub("db.pool"))
+	should.Equal(Items{"host": "s2", "weight": "2"}, root.Sub("servers[1]"))
+	// a list at the prefix is keyed like Items.Sub
+	servers := root.Sub("servers")
+	should.Equal(Items{"0.host": "s1", "1.host": "s2", "1.weight": "2"}, servers)
+	should.Equal("s2", servers.Get("1.host"))
+	should.Equal(Items{"0.host": "s1", "1.host": "s2", "1.weight": "2"}, Items{
+		"servers[0].host":   "s1",
+		"servers[1].host":   "s2",
+		"servers[1].weight": "2",
+	}.Sub("servers"))
+	should.Empty(root.Sub("db.host"))
+	should.Empty(root.Sub("cache"))
+}
+
+func TestTreeDelete(t *testing.T) {
+	should := require.New(t)
+
+	root := Items{"a.b": "1", "a.c": "2", "d": "3"}.Tree()
+
+	should.True(root.Delete("a.b"))
+	should.Nil(root.Find("a.b"))
+	should.Equal("2", root.Find("a.c").Value)
+	should.False(root.Delete("a.b"))
+	should.False(root.Delete("x.y"))
+	should.False(root.Delete(""))
+
+	should.True(root.Delete("a"))
+	should.Equal(Items{"d": "3"}, root.Sub(""))
+
+	// parents which become empty are deleted
+	root = Items{"db.host": "h", "x": "1"}.Tree()
+	should.True(root.Delete("db.host"))
+	should.Nil(root.Find("db"))
+	should.Equal(Items{"x": "1"}, root.Sub(""))
+
+	root = Items{"a.b.c[0]": "1", "a.d": "2"}.Tree()
+	should.True(root.Delete("a.b.c[0]"))
+	should.Nil(root.Find("a.b"))
+	should.Equal(Items{"a.d": "2"}, root.Sub(""))
+
+	// a parent with its own value is kept as a leaf
+	root = Items{"a": "1", "a.b": "2"}.Tree()
+	should.True(root.Delete("a.b"))
+	should.Equal(Items{"a": "1"}, root.Sub(""))
+}
@@ -0,0 +1,107 @@
+package lunar
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	xmlAttrPrefix = "@"     // prefix of attribute keys
+	xmlTextKey    = "#text" // key of text in elements with attributes or children
+)
+
+// xmlElement is an element being parsed
+type xmlElement struct {
+	name     string
+	children map[string]interface{}
+	text     strings.Builder
+}
+
+// parseXML parses xml content into a tree: the root element is the only key of the tree,
+// an element with only text is a string, otherwise it's a map[string]interface{} whose keys are
+// child element names and attribute names prefixed with @, the text is keyed by #text.
+// Repeated child elements are collected into []interface{}.
+func parseXML(content string) (interface{}, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+
+	root := &xmlElement{children: make(map[string]interface{})}
+	stack := []*xmlElement{root}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &xmlElement{
+				name:     t.Name.Local,
+				children: make(map[string]interface{}),
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				e.children[xmlAttrPrefix+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			addXMLChild(stack[len(stack)-1].children, e.name, e.value())
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("xml: unexpected end of content")
+	}
+
+	if len(root.children) == 0 {
+		return nil, nil
+	}
+
+	return root.children, nil
+}
+
+// value converts element to string or map
+func (e *xmlElement) value() interface{} {
+	text := strings.TrimSpace(e.text.String())
+
+	if len(e.children) == 0 {
+		return text
+	}
+
+	if text != "" {
+		e.children[xmlTextKey] = text
+	}
+
+	return e.children
+}
+
+// addXMLChild adds child to parent, repeated children are collected into list
+func addXMLChild(parent map[string]interface{}, name string, v interface{}) {
+	existing, ok := parent[name]
+	if !ok {
+		parent[name] = v
+		return
+	}
+
+	if list, ok := existing.([]interface{}); ok {
+		parent[name] = append(list, v)
+		return
+	}
+
+	parent[name] = []interface{}{existing, v}
+}
@@ -0,0 +1,61 @@
+package lunar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseXML(t *testing.T) {
+	should := require.New(t)
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- comment -->
+<config xmlns="http://example.com" version="2">
+  <db host="localhost" port="3306">
+    <user>root</user>
+    <password><![CDATA[p<a>ss]]></password>
+  </db>
+  <servers>
+    <server>s1</server>
+    <server>s2</server>
+    <server>s3</server>
+  </servers>
+  <timeout unit="s">30</timeout>
+  <empty/>
+</config>`
+
+	tree, err := parseXML(content)
+	should.NoError(err)
+	should.Equal(map[string]interface{}{
+		"config": map[string]interface{}{
+			"@version": "2",
+			"db": map[string]interface{}{
+				"@host":    "localhost",
+				"@port":    "3306",
+				"user":     "root",
+				"password": "p<a>ss",
+			},
+			"servers": map[string]interface{}{
+				"server": []interface{}{"s1", "s2", "s3"},
+			},
+			"timeout": map[string]interface{}{
+				"@unit": "s",
+				"#text": "30",
+			},
+			"empty": "",
+		},
+	}, tree)
+
+	should.Equal("s2", Flatten(tree).Get("config.servers.server[1]"))
+
+	tree, err = parseXML(" ")
+	should.NoError(err)
+	should.Nil(tree)
+
+	_, err = parseXML("<a><b></a>")
+	should.Error(err)
+
+	_, err = parseXML("<a>")
+	should.Error(err)
+}
@@ -0,0 +1,846 @@
+package lunar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of yaml document
+type yamlLine struct {
+	num    int    // line number, starts from 1
+	indent int    // number of leading spaces
+	text   string // text without leading spaces
+}
+
+// yamlParser parses a subset of yaml: block and flow mappings and sequences, plain and quoted scalars,
+// literal and folded block scalars, anchors, aliases and merge keys. Only the first document is parsed,
+// scalars are kept as strings and null is nil.
+type yamlParser struct {
+	lines   []yamlLine
+	pos     int
+	anchors map[string]interface{}
+}
+
+// parseYAML parses yaml content into a tree of map[string]interface{}, []interface{}, string and nil
+func parseYAML(content string) (interface{}, error) {
+	p := &yamlParser{anchors: make(map[string]interface{})}
+
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	started := false
+	for i, raw := range strings.Split(content, "\n") {
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+
+		if indent == 0 && (raw == "---" || strings.HasPrefix(raw, "--- ") || raw == "...") {
+			if started || raw == "..." {
+				break // only the first document is parsed
+			}
+			started = true
+			if rest := strings.TrimSpace(strings.TrimPrefix(raw, "---")); rest != "" && rest[0] != '#' {
+				p.lines = append(p.lines, yamlLine{num: i + 1, indent: 0, text: rest})
+			}
+			continue
+		}
+
+		if !started && indent == 0 && strings.HasPrefix(raw, "%") {
+			continue // directive
+		}
+
+		if strings.TrimSpace(text) == "" {
+			text = ""
+		} else if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed as indentation", i+1)
+		}
+
+		if text != "" && !isYAMLComment(text) {
+			started = true
+		}
+
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: indent, text: text})
+	}
+
+	v, err := p.parseBlock(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := p.nextContent(); i >= 0 {
+		return nil, p.errorf(i, "unexpected content %q", p.lines[i].text)
+	}
+
+	return v, nil
+}
+
+func isYAMLComment(text string) bool {
+	return strings.HasPrefix(text, "#")
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// isYAMLBlank checks if text has nothing but whitespace and comment
+func isYAMLBlank(text string) bool {
+	return strings.TrimSpace(stripYAMLComment(text)) == ""
+}
+
+func (p *yamlParser) errorf(i int, format string, args ...interface{}) error {
+	num := 0
+	if i >= 0 && i < len(p.lines) {
+		num = p.lines[i].num
+	}
+
+	return fmt.Errorf("yaml: line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// nextContent gets the index of next line which is neither blank nor comment, -1 if not found
+func (p *yamlParser) nextContent() int {
+	for i := p.pos; i < len(p.lines); i++ {
+		if p.lines[i].text != "" && !isYAMLComment(p.lines[i].text) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseBlock parses a node which is more indented than parent, returns nil if there is no such node
+func (p *yamlParser) parseBlock(parent int) (interface{}, error) {
+	i := p.nextContent()
+	if i < 0 || p.lines[i].indent <= parent {
+		return nil, nil
+	}
+
+	line := p.lines[i]
+	if isYAMLSeqItem(line.text) {
+		return p.parseSequence(line.indent)
+	}
+
+	if _, _, ok, err := splitYAMLKey(line.text); err != nil {
+		return nil, p.errorf(i, "%s", err.Error())
+	} else if ok {
+		return p.parseMapping(line.indent)
+	}
+
+	p.pos = i + 1
+
+	return p.parseInline(line.text, parent, i)
+}
+
+// parseMapping parses a block mapping whose keys are at given indent
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	var merges []interface{}
+
+	for {
+		i := p.nextContent()
+		if i < 0 || p.lines[i].indent < indent {
+			break
+		}
+
+		line := p.lines[i]
+		if line.indent > indent {
+			return nil, p.errorf(i, "bad indentation of a mapping entry")
+		}
+		if isYAMLSeqItem(line.text) {
+			break
+		}
+
+		key, rest, ok, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, p.errorf(i, "%s", err.Error())
+		}
+		if !ok {
+			return nil, p.errorf(i, "expected a mapping entry, got %q", line.text)
+		}
+		p.pos = i + 1
+
+		var v interface{}
+		if isYAMLBlank(rest) {
+			// the value is a nested block, a sequence may have the same indent as the key
+			if j := p.nextContent(); j >= 0 && p.lines[j].indent == indent && isYAMLSeqItem(p.lines[j].text) {
+				v, err = p.parseSequence(indent)
+			} else {
+				v, err = p.parseBlock(indent)
+			}
+		} else {
+			v, err = p.parseInline(rest, indent, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "<<" {
+			merges = append(merges, v)
+			continue
+		}
+
+		m[key] = v
+	}
+
+	// explicit keys override merged keys
+	for _, merge := range merges {
+		sources, ok := merge.([]interface{})
+		if !ok {
+			sources = []interface{}{merge}
+		}
+		for _, source := range sources {
+			sm, ok := source.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("yaml: merge key only accepts mappings")
+			}
+			for k, v := range sm {
+				if _, exists := m[k]; !exists {
+					m[k] = v
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// parseSequence parses a block sequence whose dashes are at given indent
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	list := make([]interface{}, 0)
+
+	for {
+		i := p.nextContent()
+		if i < 0 || p.lines[i].indent < indent {
+			break
+		}
+
+		line := p.lines[i]
+		if line.indent > indent {
+			return nil, p.errorf(i, "bad indentation of a sequence entry")
+		}
+		if !isYAMLSeqItem(line.text) {
+			break
+		}
+
+		trimmed := strings.TrimLeft(line.text[1:], " \t")
+
+		var v interface{}
+		var err error
+		if isYAMLBlank(trimmed) {
+			p.pos = i + 1
+			v, err = p.parseBlock(indent)
+		} else {
+			// replace the entry with a virtual line starting at the column of the item,
+			// so that compact nested mappings and sequences are parsed as blocks
+			p.lines[i] = yamlLine{
+				num:    line.num,
+				indent: indent + len(line.text) - len(trimmed),
+				text:   trimmed,
+			}
+			p.pos = i
+			v, err = p.parseBlock(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
+// parseInline parses a value starting in the middle of line i, continuation lines must be more indented than parent
+func (p *yamlParser) parseInline(text string, parent int, i int) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	// tag is ignored, scalars are always strings
+	if strings.HasPrefix(text, "!") {
+		end := strings.IndexAny(text, " \t")
+		if end < 0 {
+			return p.parseBlock(parent)
+		}
+		text = strings.TrimSpace(text[end:])
+	}
+
+	if strings.HasPrefix(text, "&") {
+		end := strings.IndexAny(text, " \t")
+		name := text[1:]
+		rest := ""
+		if end >= 0 {
+			name, rest = text[1:end], strings.TrimSpace(text[end:])
+		}
+
+		var v interface{}
+		var err error
+		if isYAMLBlank(rest) {
+			v, err = p.parseBlock(parent)
+		} else {
+			v, err = p.parseInline(rest, parent, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.anchors[name] = v
+
+		return v, nil
+	}
+
+	if strings.HasPrefix(text, "*") {
+		name := strings.TrimSpace(stripYAMLComment(text[1:]))
+		v, ok := p.anchors[name]
+		if !ok {
+			return nil, p.errorf(i, "unknown anchor %q", name)
+		}
+
+		return v, nil
+	}
+
+	switch text[0] {
+	case '|', '>':
+		return p.parseBlockScalar(text, parent, i)
+	case '[', '{':
+		return p.parseFlowLines(text, parent, i)
+	case '"', '\'':
+		return p.parseQuotedLines(text, parent, i)
+	}
+
+	return p.parsePlainLines(text, parent, i)
+}
+
+// parsePlainLines parses a plain scalar which may continue on more indented lines
+func (p *yamlParser) parsePlainLines(text string, parent int, i int) (interface{}, error) {
+	text = strings.TrimSpace(stripYAMLComment(text))
+	if strings.HasPrefix(text, "#") || text == "" {
+		return nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(text)
+
+	newlines := 0
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			newlines++
+			continue
+		}
+		if line.indent <= parent || isYAMLComment(line.text) {
+			break
+		}
+		if _, _, ok, _ := splitYAMLKey(line.text); ok {
+			return nil, p.errorf(p.pos, "mapping values are not allowed in this context")
+		}
+
+		if newlines > 0 {
+			sb.WriteString(strings.Repeat("\n", newlines))
+		} else {
+			sb.WriteByte(' ')
+		}
+		newlines = 0
+
+		cont := strings.TrimSpace(stripYAMLComment(line.text))
+		sb.WriteString(cont)
+		if cont != strings.TrimSpace(line.text) {
+			p.pos++
+			break
+		}
+	}
+
+	return resolveYAMLScalar(sb.String()), nil
+}
+
+func resolveYAMLScalar(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	}
+
+	return s
+}
+
+// parseQuotedLines parses a quoted scalar which may span multiple lines
+func (p *yamlParser) parseQuotedLines(text string, parent int, i int) (interface{}, error) {
+	quote := text[0]
+
+	for {
+		if end := findYAMLQuoteEnd(text, quote); end > 0 {
+			if rest := strings.TrimSpace(text[end+1:]); rest != "" && rest[0] != '#' {
+				return nil, p.errorf(i, "unexpected content after quoted string: %q", rest)
+			}
+
+			if quote == '\'' {
+				return strings.ReplaceAll(text[1:end], "''", "'"), nil
+			}
+
+			s, err := unquoteYAMLDouble(text[1:end])
+			if err != nil {
+				return nil, p.errorf(i, "%s", err.Error())
+			}
+
+			return s, nil
+		}
+
+		if p.pos >= len(p.lines) {
+			return nil, p.errorf(i, "unterminated quoted string")
+		}
+
+		// line breaks are folded into spaces, empty lines into newlines
+		line := p.lines[p.pos]
+		p.pos++
+		if line.text == "" {
+			text = strings.TrimRight(text, " ") + "\n"
+			continue
+		}
+		if strings.HasSuffix(text, "\n") || strings.HasSuffix(text, "\\") {
+			text += line.text
+		} else {
+			text = strings.TrimRight(text, " ") + " " + line.text
+		}
+	}
+}
+
+// findYAMLQuoteEnd finds the index of the closing quote, -1 if not found
+func findYAMLQuoteEnd(text string, quote byte) int {
+	for j := 1; j < len(text); j++ {
+		switch {
+		case quote == '"' && text[j] == '\\':
+			j++
+		case text[j] == quote:
+			if quote == '\'' && j+1 < len(text) && text[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+
+	return -1
+}
+
+func unquoteYAMLDouble(s string) (string, error) {
+	var sb strings.Builder
+
+	for j := 0; j < len(s); j++ {
+		c := s[j]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		j++
+		if j >= len(s) {
+			return "", fmt.Errorf("invalid escape at the end of %q", s)
+		}
+
+		switch s[j] {
+		case '0':
+			sb.WriteByte(0)
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 't', '\t':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			sb.WriteByte(s[j])
+		case 'N':
+			sb.WriteString("\u0085")
+		case '_':
+			sb.WriteString("\u00a0")
+		case 'L':
+			sb.WriteString("\u2028")
+		case 'P':
+			sb.WriteString("\u2029")
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[j]]
+			if j+size >= len(s) {
+				return "", fmt.Errorf("invalid escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[j+1:j+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in %q", s)
+			}
+			sb.WriteRune(rune(r))
+			j += size
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in %q", s[j], s)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// parseBlockScalar parses literal (|) and folded (>) block scalars
+func (p *yamlParser) parseBlockScalar(header string, parent int, i int) (interface{}, error) {
+	header = strings.TrimSpace(stripYAMLComment(header))
+
+	literal := header[0] == '|'
+	chomping := byte(0)
+	indent := 0
+	for j := 1; j < len(header); j++ {
+		switch c := header[j]; {
+		case c == '-' || c == '+':
+			chomping = c
+		case c >= '1' && c <= '9':
+			indent = int(c - '0')
+		default:
+			return nil, p.errorf(i, "invalid block scalar header %q", header)
+		}
+	}
+
+	// indentation indicator is relative to parent
+	if indent > 0 && parent > 0 {
+		indent += parent
+	}
+
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if indent == 0 {
+			if line.indent <= parent {
+				break
+			}
+			indent = line.indent
+		}
+		if line.indent < indent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", line.indent-indent)+line.text)
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var body string
+	if literal {
+		body = strings.Join(lines, "\n")
+	} else {
+		body = foldYAMLLines(lines)
+	}
+
+	switch {
+	case len(lines) == 0 && chomping != '+':
+		return "", nil
+	case chomping == '-':
+		return body, nil
+	case chomping == '+':
+		return body + strings.Repeat("\n", trailing+1), nil
+	default:
+		return body + "\n", nil
+	}
+}
+
+// foldYAMLLines folds lines of folded block scalar, more indented lines keep their line breaks
+func foldYAMLLines(lines []string) string {
+	var sb strings.Builder
+
+	prev := -1 // index of previous non-empty line
+	for j, line := range lines {
+		if line == "" {
+			if prev >= 0 {
+				sb.WriteByte('\n')
+			}
+			continue
+		}
+
+		more := line[0] == ' '
+		if prev >= 0 {
+			prevMore := lines[prev][0] == ' '
+			switch {
+			case prev == j-1 && !more && !prevMore:
+				sb.WriteByte(' ')
+			case prev == j-1:
+				sb.WriteByte('\n')
+			case more || prevMore:
+				sb.WriteByte('\n')
+			}
+		}
+
+		sb.WriteString(line)
+		prev = j
+	}
+
+	return sb.String()
+}
+
+// parseFlowLines parses a flow collection which may span multiple lines
+func (p *yamlParser) parseFlowLines(text string, parent int, i int) (interface{}, error) {
+	for !isYAMLFlowClosed(text) {
+		if p.pos >= len(p.lines) || (p.lines[p.pos].text != "" && p.lines[p.pos].indent <= parent) {
+			return nil, p.errorf(i, "unterminated flow collection")
+		}
+		text += " " + stripYAMLComment(p.lines[p.pos].text)
+		p.pos++
+	}
+
+	fp := &yamlFlowParser{text: text, anchors: p.anchors}
+	v, err := fp.parseValue()
+	if err != nil {
+		return nil, p.errorf(i, "%s", err.Error())
+	}
+
+	fp.skipSpaces()
+	if rest := fp.text[fp.pos:]; rest != "" && rest[0] != '#' {
+		return nil, p.errorf(i, "unexpected content after flow collection: %q", rest)
+	}
+
+	return v, nil
+}
+
+// isYAMLFlowClosed checks if all the brackets in text are closed
+func isYAMLFlowClosed(text string) bool {
+	depth := 0
+	for j := 0; j < len(text); j++ {
+		switch c := text[j]; c {
+		case '"', '\'':
+			end := findYAMLQuoteEnd(text[j:], c)
+			if end < 0 {
+				return false
+			}
+			j += end
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '#':
+			if j > 0 && text[j-1] == ' ' {
+				return depth <= 0
+			}
+		}
+	}
+
+	return depth <= 0
+}
+
+// yamlFlowParser parses flow collections like [a, b] and {a: 1, b: 2}
+type yamlFlowParser struct {
+	text    string
+	pos     int
+	anchors map[string]interface{}
+}
+
+func (fp *yamlFlowParser) skipSpaces() {
+	for fp.pos < len(fp.text) && (fp.text[fp.pos] == ' ' || fp.text[fp.pos] == '\t') {
+		fp.pos++
+	}
+}
+
+func (fp *yamlFlowParser) parseValue() (interface{}, error) {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return nil, nil
+	}
+
+	switch c := fp.text[fp.pos]; c {
+	case '[':
+		return fp.parseSequence()
+	case '{':
+		return fp.parseMapping()
+	case '"', '\'':
+		return fp.parseQuoted()
+	case '*':
+		fp.pos++
+		name := fp.parsePlain()
+		v, ok := fp.anchors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown anchor %q", name)
+		}
+		return v, nil
+	}
+
+	return resolveYAMLScalar(fp.parsePlain()), nil
+}
+
+func (fp *yamlFlowParser) parseQuoted() (interface{}, error) {
+	quote := fp.text[fp.pos]
+	end := findYAMLQuoteEnd(fp.text[fp.pos:], quote)
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+
+	s := fp.text[fp.pos+1 : fp.pos+end]
+	fp.pos += end + 1
+
+	if quote == '\'' {
+		return strings.ReplaceAll(s, "''", "'"), nil
+	}
+
+	return unquoteYAMLDouble(s)
+}
+
+// parsePlain parses a plain scalar which ends with , ] } or ": "
+func (fp *yamlFlowParser) parsePlain() string {
+	start := fp.pos
+	for fp.pos < len(fp.text) {
+		c := fp.text[fp.pos]
+		if c == ',' || c == ']' || c == '}' {
+			break
+		}
+		if c == ':' && (fp.pos+1 == len(fp.text) || strings.ContainsRune(" ,]}", rune(fp.text[fp.pos+1]))) {
+			break
+		}
+		fp.pos++
+	}
+
+	return strings.TrimSpace(fp.text[start:fp.pos])
+}
+
+func (fp *yamlFlowParser) parseSequence() (interface{}, error) {
+	fp.pos++ // skip [
+	list := make([]interface{}, 0)
+
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if fp.text[fp.pos] == ']' {
+			fp.pos++
+			return list, nil
+		}
+
+		v, err := fp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+
+		fp.skipSpaces()
+		if fp.pos < len(fp.text) && fp.text[fp.pos] == ',' {
+			fp.pos++
+		} else if fp.pos < len(fp.text) && fp.text[fp.pos] != ']' {
+			return nil, fmt.Errorf("expected , or ] in flow sequence")
+		}
+	}
+}
+
+func (fp *yamlFlowParser) parseMapping() (interface{}, error) {
+	fp.pos++ // skip {
+	m := make(map[string]interface{})
+
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if fp.text[fp.pos] == '}' {
+			fp.pos++
+			return m, nil
+		}
+
+		k, err := fp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprint(k)
+		if k == nil {
+			key = ""
+		}
+
+		var v interface{}
+		fp.skipSpaces()
+		if fp.pos < len(fp.text) && fp.text[fp.pos] == ':' {
+			fp.pos++
+			if v, err = fp.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		m[key] = v
+
+		fp.skipSpaces()
+		if fp.pos < len(fp.text) && fp.text[fp.pos] == ',' {
+			fp.pos++
+		} else if fp.pos < len(fp.text) && fp.text[fp.pos] != '}' {
+			return nil, fmt.Errorf("expected , or } in flow mapping")
+		}
+	}
+}
+
+// splitYAMLKey splits "key: value" into key and value, ok is false if text is not a mapping entry
+func splitYAMLKey(text string) (string, string, bool, error) {
+	if text == "" || strings.ContainsRune("[{#|>&*!", rune(text[0])) || isYAMLSeqItem(text) {
+		return "", "", false, nil
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		end := findYAMLQuoteEnd(text, text[0])
+		if end < 0 {
+			return "", "", false, nil
+		}
+
+		rest := strings.TrimLeft(text[end+1:], " \t")
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && !isYAMLSpace(rest[1])) {
+			return "", "", false, nil
+		}
+
+		key := strings.ReplaceAll(text[1:end], "''", "'")
+		if text[0] == '"' {
+			var err error
+			if key, err = unquoteYAMLDouble(text[1:end]); err != nil {
+				return "", "", false, err
+			}
+		}
+
+		return key, strings.TrimLeft(rest[1:], " \t"), true, nil
+	}
+
+	if strings.HasPrefix(text, "? ") {
+		return "", "", false, fmt.Errorf("complex mapping keys are not supported")
+	}
+
+	for j := 0; j < len(text); j++ {
+		switch text[j] {
+		case ':':
+			if j+1 == len(text) || isYAMLSpace(text[j+1]) {
+				return strings.TrimSpace(text[:j]), strings.TrimLeft(text[j+1:], " \t"), true, nil
+			}
+		case '#':
+			if isYAMLSpace(text[j-1]) {
+				return "", "", false, nil
+			}
+		}
+	}
+
+	return "", "", false, nil
+}
+
+// stripYAMLComment removes the comment at the end of a plain text
+func stripYAMLComment(text string) string {
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+
+	for j := 1; j < len(text); j++ {
+		if text[j] == '#' && isYAMLSpace(text[j-1]) {
+			return strings.TrimRight(text[:j], " \t")
+		}
+	}
+
+	return text
+}
+
+// isYAMLSpace checks if c separates tokens, i.e. a space or a tab
+func isYAMLSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,26 @@
+//go:build go1.18
+// +build go1.18
+
+package lunar
+
+import "testing"
+
+func FuzzParseYAML(f *testing.F) {
+	for _, seed := range []string{
+		"a: 1\nb:\n  - x\n  - y: z\n",
+		"a: \t\n",
+		"- \t\n",
+		"list:\n  - \t\n",
+		"a: &x {b: [1, 2]}\nc: *x\n",
+		"a: |\n  text\nb: >\n  folded\n",
+		"a: \"quoted\n  string\"\n",
+		"--- !tag\n? complex\n",
+	} {
+		f.Add(seed)
+	}
+
+	// parseYAML must return an error rather than panic on any content
+	f.Fuzz(func(t *testing.T, content string) {
+		_, _ = parseYAML(content)
+	})
+}
@@ -0,0 +1,156 @@
+package lunar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseYAML(t *testing.T) {
+	should := require.New(t)
+
+	content := `%YAML 1.2
+---
+# comment
+server:
+  host: localhost # trailing comment
+  port: 8080
+  ports: [80, 443]
+  tags:
+  - a
+  - "b # not comment"
+  - 'it''s'
+empty:
+nothing: ~
+servers:
+  - host: s1
+    port: 1
+  - host: s2
+    port: 2
+  - - nested
+    - list
+map: {a: 1, b: [x, y], "c": 'z'}
+literal: |
+  line 1
+    line 2
+
+folded: >-
+  folded
+  text
+
+  new paragraph
+keep: |+
+  keep
+
+plain: multi
+  line plain
+url: http://localhost:8080/path
+quoted: "tab\tnewline\nunicode\u00e9"
+base: &base
+  x: 1
+  y: 2
+derived:
+  <<: *base
+  y: 3
+alias: *base
+tagged: !!str 123
+---
+second: document
+`
+
+	tree, err := parseYAML(content)
+	should.NoError(err)
+
+	should.Equal(map[string]interface{}{
+		"server": map[string]interface{}{
+			"host":  "localhost",
+			"port":  "8080",
+			"ports": []interface{}{"80", "443"},
+			"tags":  []interface{}{"a", "b # not comment", "it's"},
+		},
+		"empty":   nil,
+		"nothing": nil,
+		"servers": []interface{}{
+			map[string]interface{}{"host": "s1", "port": "1"},
+			map[string]interface{}{"host": "s2", "port": "2"},
+			[]interface{}{"nested", "list"},
+		},
+		"map": map[string]interface{}{
+			"a": "1",
+			"b": []interface{}{"x", "y"},
+			"c": "z",
+		},
+		"literal": "line 1\n  line 2\n",
+		"folded":  "folded text\nnew paragraph",
+		"keep":    "keep\n\n",
+		"plain":   "multi line plain",
+		"url":     "http://localhost:8080/path",
+		"quoted":  "tab\tnewline\nunicodeé",
+		"base":    map[string]interface{}{"x": "1", "y": "2"},
+		"derived": map[string]interface{}{"x": "1", "y": "3"},
+		"alias":   map[string]interface{}{"x": "1", "y": "2"},
+		"tagged":  "123",
+	}, tree)
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	should := require.New(t)
+
+	tests := []string{
+		"a: 1\n  b: 2",
+		"a:\n\tb: 1",
+		"a: *unknown",
+		"a: [1, 2",
+		"a: \"unterminated",
+		"- a\nb: 1",
+		"? complex\n: key",
+	}
+
+	for _, test := range tests {
+		_, err := parseYAML(test)
+		should.Error(err, test)
+	}
+}
+
+func TestParseYAMLScalarAndEmpty(t *testing.T) {
+	should := require.New(t)
+
+	tree, err := parseYAML("")
+	should.NoError(err)
+	should.Nil(tree)
+
+	tree, err = parseYAML("# only comment\n")
+	should.NoError(err)
+	should.Nil(tree)
+
+	tree, err = parseYAML("- a\n- b\n")
+	should.NoError(err)
+	should.Equal([]interface{}{"a", "b"}, tree)
+
+	tree, err = parseYAML("--- |\n  text\n")
+	should.NoError(err)
+	should.Equal("text\n", tree)
+}
+
+func TestParseYAMLTrailingWhitespace(t *testing.T) {
+	should := require.New(t)
+
+	tests := map[string]interface{}{
+		"a: \t\n":                   map[string]interface{}{"a": nil},
+		"a:\t\n":                    map[string]interface{}{"a": nil},
+		"a:\tb\n":                   map[string]interface{}{"a": "b"},
+		"a: b\t# comment\n":         map[string]interface{}{"a": "b"},
+		"- \t\n":                    []interface{}{nil},
+		"list:\n  - \t\n":           map[string]interface{}{"list": []interface{}{nil}},
+		"list:\n  - \t\n  -\tx\n":   map[string]interface{}{"list": []interface{}{nil, "x"}},
+		"a: &x \t\n  b: 1\nc: *x\n": map[string]interface{}{"a": map[string]interface{}{"b": "1"}, "c": map[string]interface{}{"b": "1"}},
+		"'quoted':\t\n":             map[string]interface{}{"quoted": nil},
+		"a:\n  b: \t\n  c: 1 \t \n": map[string]interface{}{"a": map[string]interface{}{"b": nil, "c": "1"}},
+	}
+
+	for text, expected := range tests {
+		tree, err := parseYAML(text)
+		should.NoError(err, text)
+		should.Equal(expected, tree, text)
+	}
+}